@@ -40,7 +40,15 @@ func run(args []string, w io.Writer, wErr io.Writer) (int, error) {
 	case "fetch":
 		return cli.HandleFetch(ctx, args[2:], wErr)
 	case "index":
-		return cli.HandleIndex(ctx, args[2:], wErr)
+		return cli.HandleIndex(ctx, args[2:], w, wErr)
+	case "store":
+		return cli.HandleStore(ctx, args[2:], w, wErr)
+	case "export":
+		return cli.HandleExport(args[2:], w, wErr)
+	case "analyze":
+		return cli.HandleAnalyze(ctx, args[2:], w, wErr)
+	case "report":
+		return cli.HandleReport(args[2:], w, wErr)
 	case "version":
 		_, _ = fmt.Fprintln(w, version)
 		return 0, nil
@@ -63,6 +71,10 @@ Usage:
 Commands:
   fetch     Fetch workflow runs and jobs from GitHub
   index     Index stored data in Elasticsearch
+  store     Inspect and maintain stored data
+  export    Export stored data as CSV, JSON Lines or Parquet
+  analyze   Analyze stored data
+  report    Report duration statistics of stored data
   version   Print version information
 
 Run 'gham <command> -h' for more information on a command.`)
@@ -0,0 +1,182 @@
+// Package analyze derives reports from the stored GitHub Actions data.
+package analyze
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+	"github.com/teleivo/github-action-metrics/internal/workflow"
+)
+
+// ActionUse is a reference to an action or reusable workflow in a version of
+// a workflow file.
+type ActionUse struct {
+	WorkflowID       int64  `json:"workflow_id"`
+	WorkflowFileHash string `json:"workflow_file_hash"`
+	// HeadSHA is the commit of the most recent run of the workflow file version.
+	HeadSHA string `json:"head_sha"`
+	// LastRunAt is when the most recent run of the workflow file version was created.
+	LastRunAt time.Time `json:"last_run_at"`
+	// Job is the ID of the job.
+	Job string `json:"job"`
+	// Step is the number of the step starting at 1, or 0 if the job calls a
+	// reusable workflow.
+	Step    int    `json:"step"`
+	Uses    string `json:"uses"`
+	Action  string `json:"action"`
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+	Pinned  bool   `json:"pinned"`
+}
+
+// ActionsOptions configures the Actions operation.
+type ActionsOptions struct {
+	// AllVersions lists the actions of all stored workflow file versions
+	// instead of only the version of the most recent run.
+	AllVersions bool
+	// Unpinned lists only references that are not pinned to a commit SHA.
+	Unpinned bool
+}
+
+// workflowFileVersion is a stored version of a workflow file and its most
+// recent run.
+type workflowFileVersion struct {
+	hash      string
+	headSHA   string
+	lastRunAt time.Time
+}
+
+// Actions lists the actions and reusable workflows referenced by the workflow
+// files stored using 'gham fetch workflow-files' for the given workflows.
+func Actions(store *storage.Store, workflowIDs []int64, opts *ActionsOptions) ([]ActionUse, error) {
+	if opts == nil {
+		opts = &ActionsOptions{}
+	}
+
+	var uses []ActionUse
+	for _, workflowID := range workflowIDs {
+		versions, err := workflowFileVersions(store, workflowID)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			slog.Warn("no workflow files stored, fetch them using 'gham fetch workflow-files'", "workflow_id", workflowID)
+			continue
+		}
+		if !opts.AllVersions {
+			versions = versions[:1]
+		}
+
+		for _, version := range versions {
+			data, err := store.LoadWorkflowFile(version.hash)
+			if err != nil {
+				return nil, err
+			}
+			wf, err := workflow.Parse(data)
+			if err != nil {
+				slog.Warn("skipping invalid workflow file", "workflow_id", workflowID, "hash", version.hash, "error", err)
+				continue
+			}
+			for _, use := range wf.Uses() {
+				if opts.Unpinned && use.Pinned() {
+					continue
+				}
+				uses = append(uses, ActionUse{
+					WorkflowID:       workflowID,
+					WorkflowFileHash: version.hash,
+					HeadSHA:          version.headSHA,
+					LastRunAt:        version.lastRunAt,
+					Job:              use.Job,
+					Step:             use.Step,
+					Uses:             use.Uses,
+					Action:           use.Action,
+					Ref:              use.Ref,
+					RefType:          string(use.Type),
+					Pinned:           use.Pinned(),
+				})
+			}
+		}
+	}
+	return uses, nil
+}
+
+// workflowFileVersions returns the stored workflow file versions of the runs
+// of a workflow, most recently run first.
+func workflowFileVersions(store *storage.Store, workflowID int64) ([]workflowFileVersion, error) {
+	versions := make(map[string]workflowFileVersion)
+	for data, err := range store.IterRuns(workflowID) {
+		if err != nil {
+			slog.Warn("error reading run", "error", err)
+			continue
+		}
+
+		var run struct {
+			HeadSHA   string    `json:"head_sha"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		if err := json.Unmarshal(data, &run); err != nil {
+			slog.Warn("error unmarshaling run", "error", err)
+			continue
+		}
+		if run.HeadSHA == "" {
+			continue
+		}
+		hash, err := store.WorkflowFileHash(workflowID, run.HeadSHA)
+		if err != nil {
+			return nil, err
+		}
+		if hash == "" {
+			continue
+		}
+		if version, ok := versions[hash]; !ok || run.CreatedAt.After(version.lastRunAt) {
+			versions[hash] = workflowFileVersion{hash: hash, headSHA: run.HeadSHA, lastRunAt: run.CreatedAt}
+		}
+	}
+
+	return slices.SortedFunc(maps.Values(versions), func(a, b workflowFileVersion) int {
+		return cmp.Or(b.lastRunAt.Compare(a.lastRunAt), cmp.Compare(a.hash, b.hash))
+	}), nil
+}
+
+// actionColumns are the CSV columns written by WriteActionsCSV.
+var actionColumns = []string{
+	"workflow_id", "workflow_file_hash", "head_sha", "last_run_at", "job", "step",
+	"uses", "action", "ref", "ref_type", "pinned",
+}
+
+// WriteActionsCSV writes the action uses as CSV with a header row.
+func WriteActionsCSV(w io.Writer, uses []ActionUse) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(actionColumns); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, use := range uses {
+		record := []string{
+			strconv.FormatInt(use.WorkflowID, 10),
+			use.WorkflowFileHash,
+			use.HeadSHA,
+			use.LastRunAt.Format(time.RFC3339),
+			use.Job,
+			strconv.Itoa(use.Step),
+			use.Uses,
+			use.Action,
+			use.Ref,
+			use.RefType,
+			strconv.FormatBool(use.Pinned),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing CSV record: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,88 @@
+package analyze
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestActions(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := map[int64]string{
+		1: `{"id":1,"head_sha":"aaa","created_at":"2021-10-01T10:00:00Z"}`,
+		2: `{"id":2,"head_sha":"bbb","created_at":"2021-10-20T10:00:00Z"}`,
+		3: `{"id":3,"head_sha":"ccc","created_at":"2021-10-10T10:00:00Z"}`,
+	}
+	for runID, data := range runs {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, err := store.SaveWorkflowFile(10954, "aaa", []byte(`
+jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := store.SaveWorkflowFile(10954, "bbb", []byte(`
+jobs:
+  build:
+    steps:
+      - uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11
+      - run: mvn verify
+      - uses: actions/upload-artifact@main
+  release:
+    uses: ./.github/workflows/release.yml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts *ActionsOptions
+		want []string
+	}{
+		{
+			name: "most recent version",
+			want: []string{
+				latest + " build 1 actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 sha true",
+				latest + " build 3 actions/upload-artifact@main branch false",
+				latest + " release 0 ./.github/workflows/release.yml local true",
+			},
+		},
+		{
+			name: "all versions unpinned",
+			opts: &ActionsOptions{AllVersions: true, Unpinned: true},
+			want: []string{
+				latest + " build 3 actions/upload-artifact@main branch false",
+				old + " build 1 actions/checkout@v2 tag false",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uses, err := Actions(store, []int64{10954}, tt.opts)
+			if err != nil {
+				t.Fatalf("Actions() error = %v", err)
+			}
+			if len(uses) != len(tt.want) {
+				t.Fatalf("Actions() returned %d uses, want %d: %+v", len(uses), len(tt.want), uses)
+			}
+			for i, use := range uses {
+				got := fmt.Sprintf("%s %s %d %s %s %t", use.WorkflowFileHash, use.Job, use.Step, use.Uses, use.RefType, use.Pinned)
+				if got != tt.want[i] {
+					t.Errorf("Actions()[%d] = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package analyze
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/billing"
+	"github.com/teleivo/github-action-metrics/internal/elastic"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// Groupings of the cost report in the order they are reported.
+const (
+	GroupByWorkflow = "workflow"
+	GroupByJob      = "job"
+	GroupByBranch   = "branch"
+	GroupByMonth    = "month"
+)
+
+var costGroupings = []string{GroupByWorkflow, GroupByJob, GroupByBranch, GroupByMonth}
+
+// CostRow is the estimated cost of the jobs of a workflow sharing the key of
+// a grouping like the branch they ran on.
+type CostRow struct {
+	GroupBy    string `json:"group_by"`
+	WorkflowID int64  `json:"workflow_id"`
+	// Key is the workflow name, base job name, branch or month in format
+	// 2006-01 depending on the grouping.
+	Key  string `json:"key"`
+	Jobs int    `json:"jobs"`
+	// Minutes are the job durations each rounded up to whole minutes.
+	Minutes         int64   `json:"minutes"`
+	BillableMinutes int64   `json:"billable_minutes"`
+	Cost            float64 `json:"cost"`
+	// Unpriced is the number of jobs whose runner or its price is unknown.
+	// They are not part of the cost.
+	Unpriced int `json:"unpriced"`
+}
+
+// CostOptions configures the Cost operation.
+type CostOptions struct {
+	// Prices are used to estimate the cost of jobs. Nil uses billing.DefaultPrices.
+	Prices billing.PriceTable
+	// From and To select jobs started at or after From and before To. Zero
+	// values select all jobs.
+	From time.Time
+	To   time.Time
+}
+
+// Cost estimates the cost of the jobs of the given workflows from their
+// durations and runners and reports it per workflow, job, branch and month.
+func Cost(store *storage.Store, workflowIDs []int64, opts *CostOptions) []CostRow {
+	if opts == nil {
+		opts = &CostOptions{}
+	}
+	docOpts := &elastic.DocumentOptions{
+		RunFields: []string{"name", "head_branch"},
+		Prices:    opts.Prices,
+	}
+
+	type rowKey struct {
+		groupBy    string
+		workflowID int64
+		key        string
+	}
+	rows := make(map[rowKey]*CostRow)
+	for _, workflowID := range workflowIDs {
+		for doc := range elastic.JobDocuments(store, workflowID, docOpts) {
+			job := doc.Body.(map[string]any)
+			started, err := time.Parse(time.RFC3339, stringField(job, "started_at"))
+			if err != nil {
+				continue
+			}
+			completed, err := time.Parse(time.RFC3339, stringField(job, "completed_at"))
+			if err != nil {
+				continue
+			}
+			if (!opts.From.IsZero() && started.Before(opts.From)) || (!opts.To.IsZero() && !started.Before(opts.To)) {
+				continue
+			}
+
+			keys := map[string]string{
+				GroupByWorkflow: cmp.Or(stringField(job, "run_name"), strconv.FormatInt(workflowID, 10)),
+				GroupByJob:      stringField(job, "base_name"),
+				GroupByBranch:   stringField(job, "run_head_branch"),
+				GroupByMonth:    started.UTC().Format("2006-01"),
+			}
+			cost, priced := job["cost"].(float64)
+			billable, _ := job["billable_minutes"].(int64)
+			for groupBy, key := range keys {
+				k := rowKey{groupBy, workflowID, key}
+				row, ok := rows[k]
+				if !ok {
+					row = &CostRow{GroupBy: groupBy, WorkflowID: workflowID, Key: key}
+					rows[k] = row
+				}
+				row.Jobs++
+				row.Minutes += billing.RoundedMinutes(completed.Sub(started))
+				if priced {
+					row.BillableMinutes += billable
+					row.Cost += cost
+				} else {
+					row.Unpriced++
+				}
+			}
+		}
+	}
+
+	result := make([]CostRow, 0, len(rows))
+	for _, row := range rows {
+		row.Cost = math.Round(row.Cost*100) / 100
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(a, b CostRow) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(costGroupings, a.GroupBy), slices.Index(costGroupings, b.GroupBy)),
+			cmp.Compare(a.WorkflowID, b.WorkflowID),
+			cmp.Compare(b.Cost, a.Cost),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+
+	var unpriced int
+	for _, row := range result {
+		if row.GroupBy == GroupByWorkflow {
+			unpriced += row.Unpriced
+		}
+	}
+	if unpriced > 0 {
+		slog.Warn("jobs with unknown runner or price are not part of the cost", "jobs", unpriced)
+	}
+	return result
+}
+
+// stringField returns the string value of a field or an empty string.
+func stringField(doc map[string]any, key string) string {
+	value, _ := doc[key].(string)
+	return value
+}
+
+// costColumns are the CSV columns written by WriteCostCSV.
+var costColumns = []string{"group_by", "workflow_id", "key", "jobs", "minutes", "billable_minutes", "cost", "unpriced"}
+
+// WriteCostCSV writes the cost report as CSV with a header row.
+func WriteCostCSV(w io.Writer, rows []CostRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(costColumns); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, row := range rows {
+		record := []string{
+			row.GroupBy,
+			strconv.FormatInt(row.WorkflowID, 10),
+			row.Key,
+			strconv.Itoa(row.Jobs),
+			strconv.FormatInt(row.Minutes, 10),
+			strconv.FormatInt(row.BillableMinutes, 10),
+			strconv.FormatFloat(row.Cost, 'f', 2, 64),
+			strconv.Itoa(row.Unpriced),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing CSV record: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
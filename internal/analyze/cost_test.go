@@ -0,0 +1,56 @@
+package analyze
+
+import (
+	"testing"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestCost(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := map[int64]string{
+		1: `{"id":1,"name":"Test","head_branch":"master"}`,
+		2: `{"id":2,"name":"Test","head_branch":"TECH-699"}`,
+	}
+	jobs := map[int64]string{
+		1: `{"jobs":[
+			{"id":11,"run_id":1,"name":"test (17)","labels":["ubuntu-latest"],"started_at":"2021-10-20T10:00:00Z","completed_at":"2021-10-20T10:09:30Z"},
+			{"id":12,"run_id":1,"name":"build","labels":["windows-latest"],"started_at":"2021-10-20T10:00:00Z","completed_at":"2021-10-20T10:05:00Z"}
+		]}`,
+		2: `{"jobs":[
+			{"id":21,"run_id":2,"name":"test (21)","labels":["self-hosted"],"started_at":"2021-11-02T10:00:00Z","completed_at":"2021-11-02T10:20:00Z"},
+			{"id":22,"run_id":2,"name":"build","labels":["custom-image"],"started_at":"2021-11-02T10:00:00Z","completed_at":"2021-11-02T10:03:00Z"}
+		]}`,
+	}
+	for runID, data := range runs {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveJobs(10954, runID, []byte(jobs[runID])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := Cost(store, []int64{10954}, nil)
+
+	want := []CostRow{
+		{GroupBy: GroupByWorkflow, WorkflowID: 10954, Key: "Test", Jobs: 4, Minutes: 38, BillableMinutes: 20, Cost: 0.16, Unpriced: 1},
+		{GroupBy: GroupByJob, WorkflowID: 10954, Key: "build", Jobs: 2, Minutes: 8, BillableMinutes: 10, Cost: 0.08, Unpriced: 1},
+		{GroupBy: GroupByJob, WorkflowID: 10954, Key: "test", Jobs: 2, Minutes: 30, BillableMinutes: 10, Cost: 0.08},
+		{GroupBy: GroupByBranch, WorkflowID: 10954, Key: "master", Jobs: 2, Minutes: 15, BillableMinutes: 20, Cost: 0.16},
+		{GroupBy: GroupByBranch, WorkflowID: 10954, Key: "TECH-699", Jobs: 2, Minutes: 23, Unpriced: 1},
+		{GroupBy: GroupByMonth, WorkflowID: 10954, Key: "2021-10", Jobs: 2, Minutes: 15, BillableMinutes: 20, Cost: 0.16},
+		{GroupBy: GroupByMonth, WorkflowID: 10954, Key: "2021-11", Jobs: 2, Minutes: 23, Unpriced: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Cost() returned %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Cost()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
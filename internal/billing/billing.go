@@ -0,0 +1,116 @@
+// Package billing estimates the billable minutes and cost of GitHub Actions
+// jobs run on GitHub-hosted runners.
+package billing
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"time"
+)
+
+// Multipliers are the factors by which minutes of standard GitHub-hosted
+// runners of an OS count towards the minutes included in a plan.
+var Multipliers = map[string]int64{
+	"linux":   1,
+	"windows": 2,
+	"macos":   10,
+}
+
+// PriceTable holds the price per minute in USD of runners keyed by OS, the
+// architecture if priced differently from x64 and size like linux-standard,
+// linux-arm64-standard, windows-8-core or macos-xlarge.
+type PriceTable map[string]float64
+
+// DefaultPrices are the list prices per minute of GitHub-hosted runners.
+var DefaultPrices = PriceTable{
+	"linux-standard":       0.008,
+	"linux-4-core":         0.016,
+	"linux-8-core":         0.032,
+	"linux-16-core":        0.064,
+	"linux-32-core":        0.128,
+	"linux-64-core":        0.256,
+	"linux-96-core":        0.384,
+	"linux-arm64-standard": 0.005,
+	"linux-arm64-4-core":   0.01,
+	"linux-arm64-8-core":   0.02,
+	"linux-arm64-16-core":  0.04,
+	"linux-arm64-32-core":  0.08,
+	"linux-arm64-64-core":  0.16,
+	"windows-standard":     0.016,
+	"windows-4-core":       0.032,
+	"windows-8-core":       0.064,
+	"windows-16-core":      0.128,
+	"windows-32-core":      0.256,
+	"windows-64-core":      0.512,
+	"windows-96-core":      0.768,
+	"macos-standard":       0.08,
+	"macos-large":          0.12,
+	"macos-xlarge":         0.16,
+}
+
+// LoadPriceTable reads prices from a JSON file mapping runner keys to the
+// price per minute. The prices override and extend the DefaultPrices.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading price table: %w", err)
+	}
+	var prices PriceTable
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("decoding price table %q: %w", path, err)
+	}
+	table := maps.Clone(DefaultPrices)
+	maps.Copy(table, prices)
+	return table, nil
+}
+
+// Price returns the price per minute of a runner and whether it is known.
+// Runners whose architecture has no price of its own use the price of their
+// OS and size.
+func (t PriceTable) Price(os, arch, size string) (float64, bool) {
+	if arch != "" && arch != "x64" {
+		if price, ok := t[os+"-"+arch+"-"+size]; ok {
+			return price, true
+		}
+	}
+	price, ok := t[os+"-"+size]
+	return price, ok
+}
+
+// RoundedMinutes returns the duration of a job rounded up to the next whole
+// minute as GitHub bills each job.
+func RoundedMinutes(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Minute - 1) / time.Minute)
+}
+
+// Estimate is the estimated billable minutes and cost of a job.
+type Estimate struct {
+	// Minutes is the duration rounded up to whole minutes.
+	Minutes int64
+	// BillableMinutes are the minutes counted towards the included minutes
+	// of a plan. Only standard runners use included minutes.
+	BillableMinutes int64
+	// Cost is the cost in USD if the job is not covered by included minutes.
+	Cost float64
+}
+
+// EstimateJob estimates the billable minutes and cost of a job of the given
+// duration run on a GitHub-hosted runner. It returns false if the price of
+// the runner is unknown.
+func (t PriceTable) EstimateJob(d time.Duration, os, arch, size string) (Estimate, bool) {
+	price, ok := t.Price(os, arch, size)
+	if !ok {
+		return Estimate{}, false
+	}
+	estimate := Estimate{Minutes: RoundedMinutes(d)}
+	estimate.Cost = float64(estimate.Minutes) * price
+	if size == "standard" {
+		estimate.BillableMinutes = estimate.Minutes * Multipliers[os]
+	}
+	return estimate, true
+}
@@ -0,0 +1,42 @@
+package billing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		os       string
+		arch     string
+		size     string
+		want     Estimate
+		wantOK   bool
+	}{
+		{"linux rounds up", 61 * time.Second, "linux", "x64", "standard", Estimate{Minutes: 2, BillableMinutes: 2, Cost: 0.016}, true},
+		{"windows multiplier", 3 * time.Minute, "windows", "x64", "standard", Estimate{Minutes: 3, BillableMinutes: 6, Cost: 0.048}, true},
+		{"macos multiplier", 30 * time.Second, "macos", "arm64", "standard", Estimate{Minutes: 1, BillableMinutes: 10, Cost: 0.08}, true},
+		{"larger runner", 10 * time.Minute, "linux", "x64", "8-core", Estimate{Minutes: 10, Cost: 0.32}, true},
+		{"arm runner", 10 * time.Minute, "linux", "arm64", "standard", Estimate{Minutes: 10, BillableMinutes: 10, Cost: 0.05}, true},
+		{"unknown size", time.Minute, "linux", "x64", "3-core", Estimate{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DefaultPrices.EstimateJob(tt.duration, tt.os, tt.arch, tt.size)
+			if ok != tt.wantOK {
+				t.Fatalf("EstimateJob() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if got.Minutes != tt.want.Minutes || got.BillableMinutes != tt.want.BillableMinutes || !almostEqual(got.Cost, tt.want.Cost) {
+				t.Errorf("EstimateJob() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	const epsilon = 1e-9
+	return a-b < epsilon && b-a < epsilon
+}
@@ -0,0 +1,394 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/teleivo/github-action-metrics/internal/analyze"
+	"github.com/teleivo/github-action-metrics/internal/billing"
+	"github.com/teleivo/github-action-metrics/internal/elastic"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// AnalyzeActionsConfig holds configuration for the analyze actions command.
+type AnalyzeActionsConfig struct {
+	Source      string
+	WorkflowIDs []int64
+	Options     analyze.ActionsOptions
+	Format      string
+	Output      string
+	// Index indexes the inventory into Elasticsearch instead of writing it.
+	Index   bool
+	Elastic elastic.Config
+	Naming  elastic.IndexNaming
+}
+
+// AnalyzeCostConfig holds configuration for the analyze cost command.
+type AnalyzeCostConfig struct {
+	Source      string
+	WorkflowIDs []int64
+	Options     analyze.CostOptions
+	Format      string
+	Output      string
+}
+
+// HandleAnalyze handles the analyze command and its subcommands.
+func HandleAnalyze(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	if len(args) < 1 {
+		printAnalyzeUsage(wErr)
+		return 2, nil
+	}
+
+	switch args[0] {
+	case "actions":
+		return handleAnalyzeActions(ctx, args[1:], w, wErr)
+	case "cost":
+		return handleAnalyzeCost(args[1:], w, wErr)
+	default:
+		printAnalyzeUsage(wErr)
+		return 2, nil
+	}
+}
+
+func printAnalyzeUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `Usage: gham analyze <command> [options]
+
+Commands:
+  actions  List the actions used by workflows and flag unpinned refs
+  cost     Estimate the cost of workflows on GitHub-hosted runners
+
+Run 'gham analyze <command> -h' for more information on a command.`)
+}
+
+func handleAnalyzeActions(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("analyze actions", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(wErr, `Usage: gham analyze actions [options]
+
+List every action and reusable workflow referenced by uses in the workflow
+files fetched using 'gham fetch workflow-files'. By default the workflow file
+version of the most recent run of each workflow is analyzed.
+
+Each reference gets a ref_type of sha, tag, branch, local or docker. Refs are
+not resolved so refs that look like a version such as v4 or v4.1.0 are taken
+to be tags. References are pinned if they use a commit SHA, an image digest or
+an action of the same repository. Tags and branches can be moved and are
+flagged as unpinned.
+
+With -index the inventory is indexed into the actions index instead. The
+documents of each analyzed workflow replace those indexed before.
+
+%s
+
+Options:
+`, elasticAuthUsage)
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to analyze, can be repeated (default all workflows)")
+	allVersions := fs.Bool("all-versions", false, "Analyze all stored workflow file versions instead of only the most recently run one")
+	unpinned := fs.Bool("unpinned", false, "Only list references that are not pinned")
+	format := fs.String("format", "json", "Output format: json or csv")
+	output := fs.String("output", "-", "File to write to, - for stdout")
+	index := fs.Bool("index", false, "Index the inventory into Elasticsearch instead of writing it")
+	elasticOpts := addElasticFlags(fs, "Elasticsearch URL (required with -index)")
+	namingOpts := addIndexNamingFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "json" && *format != "csv" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be json or csv")
+		fs.Usage()
+		return 2, nil
+	}
+	elasticConfig := elasticOpts.config()
+	if *index {
+		if elasticConfig.URL == "" {
+			_, _ = fmt.Fprintln(wErr, "Error: -url is required with -index")
+			fs.Usage()
+			return 2, nil
+		}
+		if !hasElasticAuth(elasticConfig) {
+			_, _ = fmt.Fprintln(wErr, "Error: Elasticsearch authentication is required")
+			_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+			return 2, nil
+		}
+	}
+	naming, err := namingOpts.naming()
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &AnalyzeActionsConfig{
+		Source:      dir,
+		WorkflowIDs: workflowIDs,
+		Options: analyze.ActionsOptions{
+			AllVersions: *allVersions,
+			Unpinned:    *unpinned,
+		},
+		Format:  *format,
+		Output:  *output,
+		Index:   *index,
+		Elastic: elasticConfig,
+		Naming:  naming,
+	}
+
+	if err := executeAnalyzeActions(ctx, config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeAnalyzeActions(ctx context.Context, config *AnalyzeActionsConfig, w io.Writer) error {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := config.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	uses, err := analyze.Actions(store, workflowIDs, &config.Options)
+	if err != nil {
+		return err
+	}
+	var unpinned int
+	for _, use := range uses {
+		if !use.Pinned {
+			unpinned++
+		}
+	}
+	slog.Info("analyzed actions", "references", len(uses), "unpinned", unpinned)
+
+	if config.Index {
+		return indexActions(ctx, config, workflowIDs, uses)
+	}
+
+	if uses == nil {
+		uses = []analyze.ActionUse{}
+	}
+	return writeReport(config.Output, w, func(w io.Writer) error {
+		if config.Format == "csv" {
+			return analyze.WriteActionsCSV(w, uses)
+		}
+		return writeJSON(w, uses)
+	})
+}
+
+// writeReport calls write with the output file, or w if output is -.
+func writeReport(output string, w io.Writer, write func(io.Writer) error) (err error) {
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing output file: %w", cerr)
+			}
+		}()
+		w = f
+	}
+	return write(w)
+}
+
+// writeJSON writes value as indented JSON.
+func writeJSON(w io.Writer, value any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+// indexActions replaces the actions documents of the analyzed workflows with
+// the action uses. Documents of a workflow are deleted first so references
+// no longer used disappear from the index.
+func indexActions(ctx context.Context, config *AnalyzeActionsConfig, workflowIDs []int64, uses []analyze.ActionUse) error {
+	client, err := connectElasticsearch(ctx, config.Elastic)
+	if err != nil {
+		return err
+	}
+
+	byWorkflow := make(map[int64][]elastic.Document)
+	for _, use := range uses {
+		body, err := documentBody(use)
+		if err != nil {
+			return err
+		}
+		byWorkflow[use.WorkflowID] = append(byWorkflow[use.WorkflowID], elastic.Document{
+			ID:   strconv.FormatInt(use.WorkflowID, 10) + "-" + use.WorkflowFileHash + "-" + use.Job + "-" + strconv.Itoa(use.Step),
+			Body: body,
+		})
+	}
+
+	for _, workflowID := range workflowIDs {
+		deleted, err := elastic.DeleteWorkflowDocuments(ctx, client, config.Naming, elastic.KindActions, workflowID)
+		if err != nil {
+			return err
+		}
+		slog.Debug("deleted previous actions", "workflow_id", workflowID, "count", deleted)
+		if len(byWorkflow[workflowID]) == 0 {
+			continue
+		}
+		if _, err := elastic.IndexDocuments(ctx, client, elastic.KindActions, workflowID, slices.Values(byWorkflow[workflowID]), config.Naming); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// documentBody returns value as a document body so that its time fields name
+// the index of the document.
+func documentBody(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding document: %w", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	}
+	return body, nil
+}
+
+func handleAnalyzeCost(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("analyze cost", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham analyze cost [options]
+
+Estimate the cost of the stored jobs and report it per workflow, job, branch
+and month.
+
+Each job is rounded up to the next whole minute and priced by its runner OS,
+architecture and size derived from its labels and the runners fetched using
+'gham fetch runners'. Billable minutes count towards the minutes included in a
+plan using the OS multipliers of standard runners: 1 for Linux, 2 for Windows
+and 10 for macOS. Larger runners do not use included minutes. Jobs on
+self-hosted runners are free.
+
+Costs are in USD using GitHub's list prices unless -prices is given. Prices
+are keyed by OS, architecture if priced differently from x64 and size like
+linux-standard, linux-arm64-standard, windows-8-core or macos-xlarge.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to analyze, can be repeated (default all workflows)")
+	pricesFile := fs.String("prices", "", "JSON file with the price per minute of runners like {\"linux-4-core\": 0.016} overriding the default prices")
+	from := fs.String("from", "", "Only include jobs started on or after this date in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	to := fs.String("to", "", "Only include jobs started on or before this day in format '2021-10-12' or before this time in format '2021-10-29T22:40:19Z'")
+	format := fs.String("format", "json", "Output format: json or csv")
+	output := fs.String("output", "-", "File to write to, - for stdout")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "json" && *format != "csv" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be json or csv")
+		fs.Usage()
+		return 2, nil
+	}
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	var prices billing.PriceTable
+	if *pricesFile != "" {
+		prices, err = billing.LoadPriceTable(*pricesFile)
+		if err != nil {
+			return 1, err
+		}
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &AnalyzeCostConfig{
+		Source:      dir,
+		WorkflowIDs: workflowIDs,
+		Options: analyze.CostOptions{
+			Prices: prices,
+			From:   start,
+			To:     end,
+		},
+		Format: *format,
+		Output: *output,
+	}
+
+	if err := executeAnalyzeCost(config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeAnalyzeCost(config *AnalyzeCostConfig, w io.Writer) error {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := config.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	rows := analyze.Cost(store, workflowIDs, &config.Options)
+	return writeReport(config.Output, w, func(w io.Writer) error {
+		if config.Format == "csv" {
+			return analyze.WriteCostCSV(w, rows)
+		}
+		return writeJSON(w, rows)
+	})
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/teleivo/github-action-metrics/internal/export"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// ExportConfig holds configuration for the export command.
+type ExportConfig struct {
+	Kinds       []export.Kind
+	Source      string
+	WorkflowIDs []int64
+	Format      string
+	Columns     []string
+	Output      string
+	Filter      export.Filter
+}
+
+// HandleExport handles the export command and its subcommands.
+func HandleExport(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	if len(args) < 1 {
+		printExportUsage(wErr)
+		return 2, nil
+	}
+
+	switch args[0] {
+	case "runs":
+		return handleExportKind("runs", []export.Kind{export.KindRuns}, args[1:], w, wErr)
+	case "jobs":
+		return handleExportKind("jobs", []export.Kind{export.KindJobs}, args[1:], w, wErr)
+	case "steps":
+		return handleExportKind("steps", []export.Kind{export.KindSteps}, args[1:], w, wErr)
+	case "all":
+		return handleExportKind("all", []export.Kind{export.KindRuns, export.KindJobs, export.KindSteps}, args[1:], w, wErr)
+	default:
+		printExportUsage(wErr)
+		return 2, nil
+	}
+}
+
+func printExportUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `Usage: gham export <command> [options]
+
+Commands:
+  runs    Export workflow runs as CSV, JSON Lines or Parquet
+  jobs    Export workflow jobs as CSV, JSON Lines or Parquet
+  steps   Export workflow steps as CSV, JSON Lines or Parquet
+  all     Export runs, jobs and steps as Parquet
+
+Run 'gham export <command> -h' for more information on a command.`)
+}
+
+func handleExportKind(name string, kinds []export.Kind, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("export "+name, flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	what := name
+	if len(kinds) > 1 {
+		what = "runs, jobs and steps"
+	}
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(wErr, `Usage: gham export %s [options]
+
+Export workflow %s as CSV, JSON Lines or Parquet. The exported documents are
+the ones 'gham index %s' indexes in Elasticsearch.
+
+For CSV and JSON Lines, nested fields are flattened into dotted column names
+like head_commit.author.name.
+
+For Parquet, -output is a directory. Files with typed columns are written
+partitioned by workflow and month, for example
+  runs/workflow_id=10954/month=2021-10/part-0.parquet
+`, name, what, name)
+		if len(kinds) == 1 {
+			_, _ = fmt.Fprintf(wErr, "\nDefault CSV columns:\n  %s\n", strings.Join(export.DefaultColumns[kinds[0]], ","))
+		}
+		_, _ = fmt.Fprintln(wErr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to export, can be repeated (default all workflows)")
+	format := fs.String("format", "csv", "Output format: csv, jsonl or parquet")
+	columns := fs.String("columns", "", "Comma-separated columns to export (default all fields for jsonl)")
+	output := fs.String("output", "-", "File to write to, - for stdout, or directory for parquet")
+	from := fs.String("from", "", "Only export documents on or after this date in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	to := fs.String("to", "", "Only export documents on or before this day in format '2021-10-12' or before this time in format '2021-10-29T22:40:19Z'")
+	conclusion := fs.String("conclusion", "", "Only export documents with one of these comma-separated conclusions")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "csv" && *format != "jsonl" && *format != "parquet" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be csv, jsonl or parquet")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format == "parquet" && (*output == "-" || *columns != "") {
+		_, _ = fmt.Fprintln(wErr, "Error: -format parquet requires an -output directory and does not support -columns")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "parquet" && len(kinds) > 1 {
+		_, _ = fmt.Fprintln(wErr, "Error: exporting all documents requires -format parquet")
+		fs.Usage()
+		return 2, nil
+	}
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &ExportConfig{
+		Kinds:       kinds,
+		Source:      dir,
+		WorkflowIDs: workflowIDs,
+		Format:      *format,
+		Columns:     splitList(*columns),
+		Output:      *output,
+		Filter: export.Filter{
+			From:        start,
+			To:          end,
+			Conclusions: splitList(*conclusion),
+		},
+	}
+	if len(config.Columns) == 0 && config.Format == "csv" {
+		config.Columns = export.DefaultColumns[kinds[0]]
+	}
+
+	if err := executeExport(config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeExport(config *ExportConfig, w io.Writer) (err error) {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := config.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.Format == "parquet" {
+		for _, kind := range config.Kinds {
+			result, err := export.ExportParquet(store, workflowIDs, kind, config.Filter, config.Output)
+			if err != nil {
+				return fmt.Errorf("exporting %s: %w", kind, err)
+			}
+			slog.Info("exported "+string(kind), "total", result.Total, "exported", result.Exported)
+		}
+		return nil
+	}
+
+	if config.Output != "-" {
+		f, err := os.Create(config.Output)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing output file: %w", cerr)
+			}
+		}()
+		w = f
+	}
+
+	var ew export.Writer
+	if config.Format == "csv" {
+		ew = export.NewCSVWriter(w, config.Columns)
+	} else {
+		ew = export.NewJSONLWriter(w, config.Columns)
+	}
+
+	kind := config.Kinds[0]
+	result, err := export.Export(store, workflowIDs, kind, config.Filter, ew)
+	if err != nil {
+		return fmt.Errorf("exporting %s: %w", kind, err)
+	}
+	if err := ew.Close(); err != nil {
+		return fmt.Errorf("exporting %s: %w", kind, err)
+	}
+
+	slog.Info("exported "+string(kind), "total", result.Total, "exported", result.Exported)
+	return nil
+}
+
+// splitList splits a comma-separated list and drops empty elements.
+func splitList(value string) []string {
+	var list []string
+	for v := range strings.SplitSeq(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
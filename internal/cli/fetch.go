@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/teleivo/github-action-metrics/internal/github"
 	"github.com/teleivo/github-action-metrics/internal/storage"
@@ -26,6 +27,7 @@ type FetchRunsConfig struct {
 	Destination string
 	Created     string
 	WithJobs    bool
+	Wait        time.Duration
 }
 
 // FetchJobsConfig holds configuration for the fetch jobs command.
@@ -34,6 +36,25 @@ type FetchJobsConfig struct {
 	Owner       string
 	WorkflowID  int64
 	Destination string
+	Wait        time.Duration
+}
+
+// FetchWorkflowFilesConfig holds configuration for the fetch workflow-files command.
+type FetchWorkflowFilesConfig struct {
+	Repo        string
+	Owner       string
+	WorkflowID  int64
+	Destination string
+	Wait        time.Duration
+}
+
+// FetchRunnersConfig holds configuration for the fetch runners command.
+type FetchRunnersConfig struct {
+	// Repo is empty to fetch the runners of the organization Owner.
+	Repo        string
+	Owner       string
+	Destination string
+	Wait        time.Duration
 }
 
 // resolveDirectory resolves a path to an absolute directory path.
@@ -70,6 +91,10 @@ func HandleFetch(ctx context.Context, args []string, wErr io.Writer) (int, error
 		return handleFetchRuns(ctx, args[1:], wErr)
 	case "jobs":
 		return handleFetchJobs(ctx, args[1:], wErr)
+	case "workflow-files":
+		return handleFetchWorkflowFiles(ctx, args[1:], wErr)
+	case "runners":
+		return handleFetchRunners(ctx, args[1:], wErr)
 	default:
 		printFetchUsage(wErr)
 		return 2, nil
@@ -80,8 +105,10 @@ func printFetchUsage(w io.Writer) {
 	_, _ = fmt.Fprintln(w, `Usage: gham fetch <command> [options]
 
 Commands:
-  runs    Fetch workflow runs from GitHub
-  jobs    Fetch jobs for stored workflow runs
+  runs            Fetch workflow runs from GitHub
+  jobs            Fetch jobs for stored workflow runs
+  workflow-files  Fetch the workflow file of stored workflow runs
+  runners         Fetch the self-hosted runners of a repository or organization
 
 Run 'gham fetch <command> -h' for more information on a command.`)
 }
@@ -106,6 +133,7 @@ Options:`)
 	destination := fs.String("destination", "", "Directory where payloads will be stored (required)")
 	created := fs.String("created", "", "Date filter in format '2021-10-12' or '2021-10-29T22:40:19Z'")
 	withJobs := fs.Bool("with-jobs", false, "Fetch jobs for fetched runs")
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
 
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -133,6 +161,7 @@ Options:`)
 		Destination: dir,
 		Created:     *created,
 		WithJobs:    *withJobs,
+		Wait:        *wait,
 	}
 
 	if err := executeFetchRuns(ctx, config); err != nil {
@@ -147,6 +176,12 @@ func executeFetchRuns(ctx context.Context, config *FetchRunsConfig) error {
 		return err
 	}
 
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	client := github.NewClient(getGitHubToken())
 
 	opts := &github.RunOptions{}
@@ -186,6 +221,7 @@ Options:`)
 	owner := fs.String("owner", "", "Owner of GitHub repository (required)")
 	workflowID := fs.Int64("workflow-id", 0, "Workflow ID of GitHub action (required)")
 	destination := fs.String("destination", "", "Directory where payloads are stored (required)")
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
 
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -211,6 +247,7 @@ Options:`)
 		Owner:       *owner,
 		WorkflowID:  *workflowID,
 		Destination: dir,
+		Wait:        *wait,
 	}
 
 	if err := executeFetchJobs(ctx, config); err != nil {
@@ -225,7 +262,156 @@ func executeFetchJobs(ctx context.Context, config *FetchJobsConfig) error {
 		return err
 	}
 
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	client := github.NewClient(getGitHubToken())
 
 	return github.FetchStoredRunJobs(ctx, client, config.Owner, config.Repo, config.WorkflowID, store)
 }
+
+func handleFetchWorkflowFiles(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("fetch workflow-files", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham fetch workflow-files [options]
+
+Fetch the workflow file of stored workflow runs at the commit each run ran on.
+Each distinct version of the workflow file is stored once by the hash of its
+contents. Indexed runs carry the hash in the workflow_file_hash field.
+
+Requires GITHUB_TOKEN environment variable for authentication.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	repo := fs.String("repo", "", "GitHub repository (required)")
+	owner := fs.String("owner", "", "Owner of GitHub repository (required)")
+	workflowID := fs.Int64("workflow-id", 0, "Workflow ID of GitHub action (required)")
+	destination := fs.String("destination", "", "Directory where payloads are stored (required)")
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *repo == "" || *owner == "" || *workflowID == 0 || *destination == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -repo, -owner, -workflow-id, and -destination are required")
+		fs.Usage()
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*destination)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &FetchWorkflowFilesConfig{
+		Repo:        *repo,
+		Owner:       *owner,
+		WorkflowID:  *workflowID,
+		Destination: dir,
+		Wait:        *wait,
+	}
+
+	if err := executeFetchWorkflowFiles(ctx, config); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeFetchWorkflowFiles(ctx context.Context, config *FetchWorkflowFilesConfig) error {
+	store, err := storage.NewStore(config.Destination)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	client := github.NewClient(getGitHubToken())
+
+	return github.FetchWorkflowFiles(ctx, client, config.Owner, config.Repo, config.WorkflowID, store)
+}
+
+func handleFetchRunners(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("fetch runners", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham fetch runners [options]
+
+Fetch the self-hosted runners of a repository, or of an organization if -repo
+is not given, and store them replacing the previous snapshot. Indexed jobs and
+steps run on a stored runner are classified using its OS and labels.
+
+Requires GITHUB_TOKEN environment variable with admin access to the repository
+or organization.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	repo := fs.String("repo", "", "GitHub repository (default the runners of the organization)")
+	owner := fs.String("owner", "", "Owner of GitHub repository or organization (required)")
+	destination := fs.String("destination", "", "Directory where payloads are stored (required)")
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *owner == "" || *destination == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -owner and -destination are required")
+		fs.Usage()
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*destination)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &FetchRunnersConfig{
+		Repo:        *repo,
+		Owner:       *owner,
+		Destination: dir,
+		Wait:        *wait,
+	}
+
+	if err := executeFetchRunners(ctx, config); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeFetchRunners(ctx context.Context, config *FetchRunnersConfig) error {
+	store, err := storage.NewStore(config.Destination)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	client := github.NewClient(getGitHubToken())
+
+	return github.FetchRunners(ctx, client, config.Owner, config.Repo, store)
+}
@@ -1,49 +1,223 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/teleivo/github-action-metrics/internal/billing"
 	"github.com/teleivo/github-action-metrics/internal/elastic"
 	"github.com/teleivo/github-action-metrics/internal/storage"
+	"github.com/teleivo/github-action-metrics/internal/workflow"
 )
 
 // IndexConfig holds configuration for index commands.
 type IndexConfig struct {
-	URL        string
+	Elastic    elastic.Config
+	Naming     elastic.IndexNaming
 	WorkflowID int64
 	Source     string
+	// Full indexes all documents instead of only those of new or changed runs.
+	Full bool
+	// Rebuild indexes all stored workflows into new indices and swaps aliases to them.
+	Rebuild bool
+	// DataStreams writes documents into data streams.
+	DataStreams bool
+	// RunFields are the run fields denormalized into job and step documents.
+	RunFields []string
+	// Workflow is the workflow definition used to compute critical paths.
+	Workflow *workflow.Workflow
+	// Prices are used to estimate the cost of jobs.
+	Prices billing.PriceTable
+	// DryRun writes the bulk request lines to Output instead of sending them.
+	DryRun bool
+	Output string
 }
 
-// getElasticsearchUser returns the Elasticsearch user from the ELASTICSEARCH_USER environment variable.
-func getElasticsearchUser() string {
-	return os.Getenv("ELASTICSEARCH_USER")
+// IndexSetupConfig holds configuration for the index setup command.
+type IndexSetupConfig struct {
+	Elastic elastic.Config
+	Naming  elastic.IndexNaming
+	Policy  elastic.LifecyclePolicy
 }
 
-// getElasticsearchPassword returns the Elasticsearch password from the ELASTICSEARCH_PASSWORD environment variable.
-func getElasticsearchPassword() string {
-	return os.Getenv("ELASTICSEARCH_PASSWORD")
+// IndexDeleteConfig holds configuration for the index delete command.
+type IndexDeleteConfig struct {
+	Elastic elastic.Config
+	Naming  elastic.IndexNaming
+	Filter  elastic.RunFilter
+	Yes     bool
+}
+
+// indexOptions returns the options for indexing documents.
+func (c *IndexConfig) indexOptions() *elastic.IndexOptions {
+	return &elastic.IndexOptions{
+		Naming:      c.Naming,
+		Full:        c.Full,
+		DataStreams: c.DataStreams,
+		Documents: elastic.DocumentOptions{
+			RunFields: c.RunFields,
+			Workflow:  c.Workflow,
+			Prices:    c.Prices,
+		},
+	}
+}
+
+// IndexKibanaConfig holds configuration for the index kibana command.
+type IndexKibanaConfig struct {
+	Kibana elastic.Config
+	Naming elastic.IndexNaming
+}
+
+// elasticAuthUsage describes how to authenticate with Elasticsearch.
+const elasticAuthUsage = `Authenticate using one of
+  - ELASTICSEARCH_USER and ELASTICSEARCH_PASSWORD environment variables
+  - -api-key or ELASTICSEARCH_API_KEY environment variable
+  - -bearer-token or ELASTICSEARCH_BEARER_TOKEN environment variable
+  - -client-cert and -client-key or ELASTICSEARCH_CLIENT_CERT and
+    ELASTICSEARCH_CLIENT_KEY environment variables
+
+TLS options can also be set using ELASTICSEARCH_CA_CERT and
+ELASTICSEARCH_INSECURE_SKIP_VERIFY environment variables.`
+
+// elasticFlags holds the flags configuring the connection to Elasticsearch.
+type elasticFlags struct {
+	url                *string
+	apiKey             *string
+	bearerToken        *string
+	caCert             *string
+	clientCert         *string
+	clientKey          *string
+	insecureSkipVerify *bool
+	maxRetries         *int
+	maxRetryTime       *time.Duration
+}
+
+// addElasticFlags registers the flags configuring the connection to Elasticsearch.
+func addElasticFlags(fs *flag.FlagSet, urlUsage string) *elasticFlags {
+	return &elasticFlags{
+		url:                fs.String("url", "", urlUsage),
+		apiKey:             fs.String("api-key", "", "Elasticsearch API key (base64 encoded)"),
+		bearerToken:        fs.String("bearer-token", "", "Elasticsearch bearer token"),
+		caCert:             fs.String("ca-cert", "", "PEM file with certificate authorities to trust"),
+		clientCert:         fs.String("client-cert", "", "PEM file with client certificate for mutual TLS"),
+		clientKey:          fs.String("client-key", "", "PEM file with client key for mutual TLS"),
+		insecureSkipVerify: fs.Bool("insecure-skip-verify", false, "Skip verification of the server certificate (insecure)"),
+		maxRetries:         fs.Int("max-retries", elastic.DefaultMaxRetries, "Maximum number of retries of a request failing with a connection error or status 429, 502, 503 or 504 (0 disables retries)"),
+		maxRetryTime:       fs.Duration("max-retry-time", elastic.DefaultMaxRetryTime, "Maximum time spent on a request including retries"),
+	}
+}
+
+// config returns the connection config. Options not set by flags are read
+// from environment variables.
+func (f *elasticFlags) config() elastic.Config {
+	insecureSkipVerify := *f.insecureSkipVerify
+	if !insecureSkipVerify {
+		insecureSkipVerify, _ = strconv.ParseBool(os.Getenv("ELASTICSEARCH_INSECURE_SKIP_VERIFY"))
+	}
+	maxRetries := *f.maxRetries
+	if maxRetries == 0 {
+		// a zero config value selects the default
+		maxRetries = -1
+	}
+	return elastic.Config{
+		URL:                *f.url,
+		Username:           os.Getenv("ELASTICSEARCH_USER"),
+		Password:           os.Getenv("ELASTICSEARCH_PASSWORD"),
+		APIKey:             flagOrEnv(*f.apiKey, "ELASTICSEARCH_API_KEY"),
+		BearerToken:        flagOrEnv(*f.bearerToken, "ELASTICSEARCH_BEARER_TOKEN"),
+		CACert:             flagOrEnv(*f.caCert, "ELASTICSEARCH_CA_CERT"),
+		ClientCert:         flagOrEnv(*f.clientCert, "ELASTICSEARCH_CLIENT_CERT"),
+		ClientKey:          flagOrEnv(*f.clientKey, "ELASTICSEARCH_CLIENT_KEY"),
+		InsecureSkipVerify: insecureSkipVerify,
+		MaxRetries:         maxRetries,
+		MaxRetryTime:       *f.maxRetryTime,
+	}
+}
+
+// indexNamingFlags holds the flags configuring the index names.
+type indexNamingFlags struct {
+	prefix   *string
+	template *string
+	owner    *string
+	repo     *string
+}
+
+// addIndexNamingFlags registers the flags configuring the index names.
+func addIndexNamingFlags(fs *flag.FlagSet) *indexNamingFlags {
+	return &indexNamingFlags{
+		prefix:   fs.String("index-prefix", "", "Prefix prepended to index names"),
+		template: fs.String("index-template", elastic.DefaultIndexTemplate, "Index name template with placeholders {kind}, {owner}, {repo}, {workflow_id}, {yyyy} and {MM}"),
+		owner:    fs.String("owner", "", "Owner of GitHub repository used in index names"),
+		repo:     fs.String("repo", "", "GitHub repository used in index names"),
+	}
+}
+
+// naming returns the validated index naming.
+func (f *indexNamingFlags) naming() (elastic.IndexNaming, error) {
+	naming := elastic.IndexNaming{
+		Prefix:   *f.prefix,
+		Template: *f.template,
+		Owner:    *f.owner,
+		Repo:     *f.repo,
+	}
+	return naming, naming.Validate()
+}
+
+// flagOrEnv returns the flag value or if empty the value of the environment variable.
+func flagOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// hasElasticAuth reports whether config holds credentials for any authentication method.
+func hasElasticAuth(config elastic.Config) bool {
+	return (config.Username != "" && config.Password != "") ||
+		config.APIKey != "" ||
+		config.BearerToken != "" ||
+		(config.ClientCert != "" && config.ClientKey != "")
+}
+
+// connectElasticsearch creates a client for the cluster and verifies that its
+// distribution and version are supported.
+func connectElasticsearch(ctx context.Context, config elastic.Config) (*elastic.Client, error) {
+	client, err := elastic.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", config.URL, err)
+	}
+	slog.Info("connected to cluster", "distribution", info.Distribution, "version", info.Version)
+	return client, nil
 }
 
 // HandleIndex handles the index command and its subcommands.
-func HandleIndex(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+func HandleIndex(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
 	if len(args) < 1 {
 		printIndexUsage(wErr)
 		return 2, nil
 	}
 
 	switch args[0] {
-	case "runs":
-		return handleIndexRuns(ctx, args[1:], wErr)
-	case "jobs":
-		return handleIndexJobs(ctx, args[1:], wErr)
-	case "steps":
-		return handleIndexSteps(ctx, args[1:], wErr)
-	case "all":
-		return handleIndexAll(ctx, args[1:], wErr)
+	case "runs", "jobs", "steps", "all":
+		return handleIndexKind(ctx, args[0], args[1:], w, wErr)
+	case "delete":
+		return handleIndexDelete(ctx, args[1:], os.Stdin, w, wErr)
+	case "setup":
+		return handleIndexSetup(ctx, args[1:], wErr)
+	case "kibana":
+		return handleIndexKibana(ctx, args[1:], wErr)
 	default:
 		printIndexUsage(wErr)
 		return 2, nil
@@ -58,6 +232,9 @@ Commands:
   jobs    Index workflow jobs in Elasticsearch
   steps   Index workflow steps in Elasticsearch
   all     Index runs, jobs, and steps in Elasticsearch
+  delete  Delete runs, jobs, and steps from Elasticsearch
+  setup   Install a lifecycle policy and data stream templates
+  kibana  Create Kibana index patterns for the indices
 
 Run 'gham index <command> -h' for more information on a command.`)
 }
@@ -68,18 +245,65 @@ func parseIndexFlags(name string, args []string, wErr io.Writer) (*IndexConfig,
 	fs.Usage = func() {
 		_, _ = fmt.Fprintf(wErr, `Usage: gham index %s [options]
 
-Index workflow %s in Elasticsearch.
+Index workflow %s in Elasticsearch 7.x or newer or OpenSearch 2.x or newer.
 
-Requires ELASTICSEARCH_USER and ELASTICSEARCH_PASSWORD environment variables for authentication.
+Only documents of runs whose stored files are new or changed since they were
+last indexed into the same cluster and indices or data streams are sent. The state is kept in
+the index-state directory of the source. Use -full to send all documents.
+
+With -data-streams documents are written into data streams with an @timestamp
+taken from run_started_at or started_at. Run 'gham index setup' first. Data
+streams are append-only so documents of changed runs are not updated.
+
+Job and step documents get the -run-fields of their run. Changing -run-fields,
+-prices or -workflow-file sends all documents again.
+
+The critical path of each run is computed from the needs of the workflow jobs
+and the job timestamps. Runs get the critical_path job names and jobs their
+slack_ms and whether they are on the critical_path. The workflow file fetched
+using 'gham fetch workflow-files' for the commit of each run is used unless
+-workflow-file is given. Runs also get the workflow_file_hash of that version.
+
+Jobs get their base_name without the matrix values GitHub appends to names of
+matrix jobs like test (ubuntu-latest, 17) and the matrix_values. If the
+workflow file is known only names of its matrix jobs are split and the values
+are also mapped to the matrix keys in matrix fields like matrix.java. Steps get
+these fields of their job prefixed by job_ like job_matrix_values.
+
+Jobs and steps get the runner_os, runner_arch, runner_type and runner_size of
+their runner derived from the job labels and the self-hosted runners fetched
+using 'gham fetch runners'. Jobs also get their estimated billable_minutes and
+cost in USD. Jobs on self-hosted runners are free.
+
+With -dry-run the bulk action and document lines are written to -output
+instead of being sent. -url is optional with -dry-run and only used to skip
+documents that are already indexed.
+
+%s
 
 Options:
-`, name, name)
+`, name, name, elasticAuthUsage)
 		fs.PrintDefaults()
 	}
 
-	url := fs.String("url", "", "Elasticsearch URL (required)")
+	elasticOpts := addElasticFlags(fs, "Elasticsearch URL (required)")
 	workflowID := fs.Int64("workflow-id", 0, "Workflow ID of GitHub action (required)")
 	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	namingOpts := addIndexNamingFlags(fs)
+	bulkWorkers := fs.Int("bulk-workers", elastic.DefaultBulkWorkers, "Number of bulk requests sent in parallel")
+	bulkActions := fs.Int("bulk-size", elastic.DefaultBulkActions, "Maximum number of documents per bulk request")
+	bulkBytes := fs.Int("bulk-bytes", elastic.DefaultBulkBytes, "Maximum size of a bulk request in bytes")
+	full := fs.Bool("full", false, "Index all documents instead of only those of runs that are new or changed since they were last indexed")
+	runFields := fs.String("run-fields", strings.Join(elastic.DefaultRunFields, ","), "Comma-separated run fields to copy into job and step documents as run_<field> with dots replaced by underscores, empty for none")
+	workflowFile := fs.String("workflow-file", "", "Workflow YAML file used to compute the critical path of runs from the needs of its jobs instead of the fetched workflow files")
+	pricesFile := fs.String("prices", "", "JSON file with the price per minute of runners like {\"linux-4-core\": 0.016} overriding the default prices")
+	dataStreams := fs.Bool("data-streams", false, "Write documents into data streams set up using 'gham index setup'")
+	dryRun := fs.Bool("dry-run", false, "Write the bulk request lines to -output instead of sending them")
+	output := fs.String("output", "-", "File to write to with -dry-run, - for stdout")
+	var rebuild *bool
+	if name == "all" {
+		rebuild = fs.Bool("rebuild", false, "Index all stored workflows into new indices, then atomically swap aliases to them and delete the old indices")
+	}
 
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -89,101 +313,433 @@ Options:
 	}
 
 	// Validate required flags
-	if *url == "" || *workflowID == 0 || *source == "" {
+	isRebuild := rebuild != nil && *rebuild
+	switch {
+	case isRebuild && (*dryRun || *dataStreams):
+		_, _ = fmt.Fprintln(wErr, "Error: -rebuild cannot be used with -dry-run or -data-streams")
+		fs.Usage()
+		return nil, 2, nil
+	case isRebuild:
+		if *elasticOpts.url == "" || *source == "" {
+			_, _ = fmt.Fprintln(wErr, "Error: -url and -source are required")
+			fs.Usage()
+			return nil, 2, nil
+		}
+		if *workflowID != 0 {
+			_, _ = fmt.Fprintln(wErr, "Error: -workflow-id cannot be used with -rebuild as all stored workflows are indexed")
+			fs.Usage()
+			return nil, 2, nil
+		}
+	case *dryRun:
+		if *workflowID == 0 || *source == "" {
+			_, _ = fmt.Fprintln(wErr, "Error: -workflow-id and -source are required")
+			fs.Usage()
+			return nil, 2, nil
+		}
+	case *elasticOpts.url == "" || *workflowID == 0 || *source == "":
 		_, _ = fmt.Fprintln(wErr, "Error: -url, -workflow-id, and -source are required")
 		fs.Usage()
 		return nil, 2, nil
 	}
 
-	// Validate required environment variables
-	if getElasticsearchUser() == "" || getElasticsearchPassword() == "" {
-		_, _ = fmt.Fprintln(wErr, "Error: ELASTICSEARCH_USER and ELASTICSEARCH_PASSWORD environment variables are required")
+	if *bulkWorkers < 1 || *bulkActions < 1 || *bulkBytes < 1 {
+		_, _ = fmt.Fprintln(wErr, "Error: -bulk-workers, -bulk-size and -bulk-bytes must be positive")
+		fs.Usage()
 		return nil, 2, nil
 	}
 
+	elasticConfig := elasticOpts.config()
+	if elasticConfig.URL != "" && !hasElasticAuth(elasticConfig) {
+		_, _ = fmt.Fprintln(wErr, "Error: Elasticsearch authentication is required")
+		_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+		return nil, 2, nil
+	}
+	elasticConfig.BulkWorkers = *bulkWorkers
+	elasticConfig.BulkActions = *bulkActions
+	elasticConfig.BulkBytes = *bulkBytes
+	naming, err := namingOpts.naming()
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return nil, 2, nil
+	}
+
+	var wf *workflow.Workflow
+	if *workflowFile != "" {
+		data, err := os.ReadFile(*workflowFile)
+		if err != nil {
+			return nil, 1, fmt.Errorf("reading workflow file: %w", err)
+		}
+		wf, err = workflow.Parse(data)
+		if err != nil {
+			return nil, 1, fmt.Errorf("invalid workflow file %q: %w", *workflowFile, err)
+		}
+	}
+
+	var prices billing.PriceTable
+	if *pricesFile != "" {
+		prices, err = billing.LoadPriceTable(*pricesFile)
+		if err != nil {
+			return nil, 1, err
+		}
+	}
+
+	fields := splitList(*runFields)
+	if fields == nil {
+		// an empty list selects no fields instead of the default ones
+		fields = []string{}
+	}
+
 	dir, err := resolveDirectory(*source)
 	if err != nil {
 		return nil, 1, err
 	}
 
 	return &IndexConfig{
-		URL:        *url,
-		WorkflowID: *workflowID,
-		Source:     dir,
+		Elastic:     elasticConfig,
+		Naming:      naming,
+		WorkflowID:  *workflowID,
+		Source:      dir,
+		Full:        *full,
+		Rebuild:     isRebuild,
+		DataStreams: *dataStreams,
+		RunFields:   fields,
+		Workflow:    wf,
+		Prices:      prices,
+		DryRun:      *dryRun,
+		Output:      *output,
 	}, 0, nil
 }
 
-func handleIndexRuns(ctx context.Context, args []string, wErr io.Writer) (int, error) {
-	config, code, err := parseIndexFlags("runs", args, wErr)
+func handleIndexKind(ctx context.Context, kind string, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	config, code, err := parseIndexFlags(kind, args, wErr)
 	if config == nil {
 		return code, err
 	}
 
+	if err := executeIndex(ctx, kind, config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+// executeIndex indexes documents of the given kind, or of all kinds for
+// "all", or writes them to the output on a dry run.
+func executeIndex(ctx context.Context, kind string, config *IndexConfig, w io.Writer) (err error) {
 	store, err := storage.NewStore(config.Source)
 	if err != nil {
-		return 1, err
+		return err
+	}
+
+	var client *elastic.Client
+	switch {
+	case config.DryRun && config.Elastic.URL != "":
+		// only used to look up the index state, no requests are sent
+		client, err = elastic.NewClient(config.Elastic)
+	case !config.DryRun:
+		client, err = connectElasticsearch(ctx, config.Elastic)
+	}
+	if err != nil {
+		return err
 	}
 
-	client := elastic.NewClient(config.URL, getElasticsearchUser(), getElasticsearchPassword())
+	opts := config.indexOptions()
+	if config.DryRun {
+		if config.Output != "-" {
+			f, err := os.Create(config.Output)
+			if err != nil {
+				return fmt.Errorf("creating output file: %w", err)
+			}
+			defer func() {
+				if cerr := f.Close(); cerr != nil && err == nil {
+					err = fmt.Errorf("closing output file: %w", cerr)
+				}
+			}()
+			w = f
+		}
+		bw := bufio.NewWriter(w)
+		defer func() {
+			if ferr := bw.Flush(); ferr != nil && err == nil {
+				err = fmt.Errorf("writing output: %w", ferr)
+			}
+		}()
+		opts.DryRun = bw
+	}
 
-	if _, err := elastic.IndexRuns(ctx, client, store, config.WorkflowID); err != nil {
-		return 1, err
+	switch kind {
+	case "runs":
+		_, err = elastic.IndexRuns(ctx, client, store, config.WorkflowID, opts)
+	case "jobs":
+		_, err = elastic.IndexJobs(ctx, client, store, config.WorkflowID, opts)
+	case "steps":
+		_, err = elastic.IndexSteps(ctx, client, store, config.WorkflowID, opts)
+	case "all":
+		if !config.Rebuild {
+			return elastic.IndexAll(ctx, client, store, config.WorkflowID, opts)
+		}
+		workflowIDs, err := store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+		return elastic.Rebuild(ctx, client, store, workflowIDs, opts)
 	}
-	return 0, nil
+	return err
 }
 
-func handleIndexJobs(ctx context.Context, args []string, wErr io.Writer) (int, error) {
-	config, code, err := parseIndexFlags("jobs", args, wErr)
-	if config == nil {
-		return code, err
+func handleIndexKibana(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("index kibana", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(wErr, `Usage: gham index kibana [options]
+
+Create Kibana index patterns for the runs, jobs and steps indices. Each index
+pattern has a duration runtime field. Pass the same index naming options as to
+'gham index' so the index patterns match the indices.
+
+Make sure to index data first so Kibana can discover the fields.
+
+%s
+
+Options:
+`, elasticAuthUsage)
+		fs.PrintDefaults()
 	}
 
-	store, err := storage.NewStore(config.Source)
+	kibanaOpts := addElasticFlags(fs, "Kibana URL (required)")
+	namingOpts := addIndexNamingFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *kibanaOpts.url == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -url is required")
+		fs.Usage()
+		return 2, nil
+	}
+	kibanaConfig := kibanaOpts.config()
+	if !hasElasticAuth(kibanaConfig) {
+		_, _ = fmt.Fprintln(wErr, "Error: authentication is required")
+		_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+		return 2, nil
+	}
+	naming, err := namingOpts.naming()
 	if err != nil {
-		return 1, err
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
 	}
 
-	client := elastic.NewClient(config.URL, getElasticsearchUser(), getElasticsearchPassword())
+	config := &IndexKibanaConfig{
+		Kibana: kibanaConfig,
+		Naming: naming,
+	}
 
-	if _, err := elastic.IndexJobs(ctx, client, store, config.WorkflowID); err != nil {
+	kibana, err := elastic.NewClient(config.Kibana)
+	if err != nil {
+		return 1, err
+	}
+	if err := elastic.CreateIndexPatterns(ctx, kibana, config.Naming); err != nil {
 		return 1, err
 	}
 	return 0, nil
 }
 
-func handleIndexSteps(ctx context.Context, args []string, wErr io.Writer) (int, error) {
-	config, code, err := parseIndexFlags("steps", args, wErr)
-	if config == nil {
-		return code, err
+func handleIndexDelete(ctx context.Context, args []string, in io.Reader, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("index delete", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(wErr, `Usage: gham index delete [options]
+
+Delete runs matching all given filters together with their jobs and steps from
+the indices. Runs are looked up in the runs indices, so jobs and steps of runs
+that are not indexed are not deleted. At least one filter is required.
+
+Asks for confirmation unless -yes is given.
+
+%s
+
+Options:
+`, elasticAuthUsage)
+		fs.PrintDefaults()
 	}
 
-	store, err := storage.NewStore(config.Source)
+	elasticOpts := addElasticFlags(fs, "Elasticsearch URL (required)")
+	namingOpts := addIndexNamingFlags(fs)
+	workflowID := fs.Int64("workflow-id", 0, "Delete runs of this workflow ID")
+	repository := fs.String("repository", "", "Delete runs of this repository given as owner/repo")
+	from := fs.String("from", "", "Delete runs created at or after this date in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	to := fs.String("to", "", "Delete runs created before this date or on this day in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *elasticOpts.url == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -url is required")
+		fs.Usage()
+		return 2, nil
+	}
+	start, end, err := parseDateRange(*from, *to)
 	if err != nil {
-		return 1, err
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		fs.Usage()
+		return 2, nil
+	}
+	filter := elastic.RunFilter{
+		WorkflowID: *workflowID,
+		Repository: *repository,
+		From:       start,
+		To:         end,
+	}
+	if filter.IsZero() {
+		_, _ = fmt.Fprintln(wErr, "Error: one of -workflow-id, -repository, -from or -to is required")
+		fs.Usage()
+		return 2, nil
+	}
+	elasticConfig := elasticOpts.config()
+	if !hasElasticAuth(elasticConfig) {
+		_, _ = fmt.Fprintln(wErr, "Error: Elasticsearch authentication is required")
+		_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+		return 2, nil
+	}
+	naming, err := namingOpts.naming()
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
 	}
 
-	client := elastic.NewClient(config.URL, getElasticsearchUser(), getElasticsearchPassword())
+	config := &IndexDeleteConfig{
+		Elastic: elasticConfig,
+		Naming:  naming,
+		Filter:  filter,
+		Yes:     *yes,
+	}
 
-	if _, err := elastic.IndexSteps(ctx, client, store, config.WorkflowID); err != nil {
+	if err := executeIndexDelete(ctx, config, in, w); err != nil {
 		return 1, err
 	}
 	return 0, nil
 }
 
-func handleIndexAll(ctx context.Context, args []string, wErr io.Writer) (int, error) {
-	config, code, err := parseIndexFlags("all", args, wErr)
-	if config == nil {
-		return code, err
+func executeIndexDelete(ctx context.Context, config *IndexDeleteConfig, in io.Reader, w io.Writer) error {
+	client, err := connectElasticsearch(ctx, config.Elastic)
+	if err != nil {
+		return err
 	}
 
-	store, err := storage.NewStore(config.Source)
+	runIDs, err := elastic.FindRunIDs(ctx, client, config.Naming, config.Filter)
 	if err != nil {
-		return 1, err
+		return err
+	}
+	if len(runIDs) == 0 {
+		_, err := fmt.Fprintln(w, "No matching runs found")
+		return err
+	}
+
+	if !config.Yes {
+		_, _ = fmt.Fprintf(w, "Delete %d runs and their jobs and steps from %s, %s and %s? [y/N] ",
+			len(runIDs), config.Naming.Pattern(elastic.KindRuns), config.Naming.Pattern(elastic.KindJobs), config.Naming.Pattern(elastic.KindSteps))
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			_, err := fmt.Fprintln(w, "Aborted")
+			return err
+		}
+	}
+
+	result, err := elastic.DeleteRuns(ctx, client, config.Naming, runIDs)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Deleted %d runs, %d jobs and %d steps\n", result.Runs, result.Jobs, result.Steps)
+	return err
+}
+
+func handleIndexSetup(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("index setup", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(wErr, `Usage: gham index setup [options]
+
+Install a lifecycle policy and index templates so that the runs, jobs and steps
+indices are created as data streams when indexing with -data-streams. Pass the
+same index naming options as to 'gham index'.
+
+Elasticsearch 7.9 or newer uses an ILM policy, OpenSearch an ISM policy. Ages
+are given like 30d or 12h and sizes like 50gb. Existing policies and templates
+of the same name are replaced.
+
+%s
+
+Options:
+`, elasticAuthUsage)
+		fs.PrintDefaults()
 	}
 
-	client := elastic.NewClient(config.URL, getElasticsearchUser(), getElasticsearchPassword())
+	elasticOpts := addElasticFlags(fs, "Elasticsearch URL (required)")
+	namingOpts := addIndexNamingFlags(fs)
+	policyName := fs.String("policy", elastic.DefaultPolicyName, "Name of the lifecycle policy, also used as prefix of the index templates")
+	rolloverMaxAge := fs.String("rollover-max-age", "30d", "Roll over to a new backing index after this age")
+	rolloverMaxSize := fs.String("rollover-max-size", "50gb", "Roll over to a new backing index once the primary shard reaches this size")
+	warmAfter := fs.String("warm-after", "", "Make backing indices read-only and force merge them after this age (default no warm phase)")
+	deleteAfter := fs.String("delete-after", "", "Delete backing indices after this age (default keep forever)")
 
-	if err := elastic.IndexAll(ctx, client, store, config.WorkflowID); err != nil {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *elasticOpts.url == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -url is required")
+		fs.Usage()
+		return 2, nil
+	}
+	policy := elastic.LifecyclePolicy{
+		Name:            *policyName,
+		RolloverMaxAge:  *rolloverMaxAge,
+		RolloverMaxSize: *rolloverMaxSize,
+		WarmAfter:       *warmAfter,
+		DeleteAfter:     *deleteAfter,
+	}
+	if err := policy.Validate(); err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		fs.Usage()
+		return 2, nil
+	}
+	elasticConfig := elasticOpts.config()
+	if !hasElasticAuth(elasticConfig) {
+		_, _ = fmt.Fprintln(wErr, "Error: Elasticsearch authentication is required")
+		_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+		return 2, nil
+	}
+	naming, err := namingOpts.naming()
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	config := &IndexSetupConfig{
+		Elastic: elasticConfig,
+		Naming:  naming,
+		Policy:  policy,
+	}
+
+	client, err := connectElasticsearch(ctx, config.Elastic)
+	if err != nil {
+		return 1, err
+	}
+	if err := elastic.Setup(ctx, client, config.Naming, config.Policy); err != nil {
 		return 1, err
 	}
 	return 0, nil
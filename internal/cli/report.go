@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/teleivo/github-action-metrics/internal/export"
+	"github.com/teleivo/github-action-metrics/internal/report"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// ReportDurationsConfig holds configuration for the report durations command.
+type ReportDurationsConfig struct {
+	Source      string
+	WorkflowIDs []int64
+	Options     report.DurationsOptions
+	Format      string
+	Output      string
+}
+
+// HandleReport handles the report command and its subcommands.
+func HandleReport(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	if len(args) < 1 {
+		printReportUsage(wErr)
+		return 2, nil
+	}
+
+	switch args[0] {
+	case "durations":
+		return handleReportDurations(args[1:], w, wErr)
+	default:
+		printReportUsage(wErr)
+		return 2, nil
+	}
+}
+
+func printReportUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `Usage: gham report <command> [options]
+
+Commands:
+  durations  Report duration percentiles of runs, jobs or steps
+
+Run 'gham report <command> -h' for more information on a command.`)
+}
+
+func handleReportDurations(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("report durations", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham report durations [options]
+
+Report the count, mean and p50, p75, p90 and p99 of the durations of stored
+runs, jobs or steps without Elasticsearch. A run lasts from the start of its
+first job to the completion of its last job.
+
+Durations are grouped by workflow, job, step, branch or ISO week. Runs cannot
+be grouped by job or step and jobs cannot be grouped by step.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to report on, can be repeated (default all workflows)")
+	kind := fs.String("kind", "runs", "Durations of runs, jobs or steps")
+	groupBy := fs.String("group-by", report.GroupByWorkflow, "Group durations by workflow, job, step, branch or week")
+	format := fs.String("format", report.FormatTable, "Output format: table, markdown, json or csv")
+	output := fs.String("output", "-", "File to write to, - for stdout")
+	from := fs.String("from", "", "Only include documents on or after this date in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	to := fs.String("to", "", "Only include documents on or before this day in format '2021-10-12' or before this time in format '2021-10-29T22:40:19Z'")
+	conclusion := fs.String("conclusion", "", "Only include documents with one of these comma-separated conclusions")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	k := export.Kind(*kind)
+	if k != export.KindRuns && k != export.KindJobs && k != export.KindSteps {
+		_, _ = fmt.Fprintln(wErr, "Error: -kind must be runs, jobs or steps")
+		fs.Usage()
+		return 2, nil
+	}
+	if err := report.ValidateGrouping(k, *groupBy); err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		fs.Usage()
+		return 2, nil
+	}
+	switch *format {
+	case report.FormatTable, report.FormatMarkdown, report.FormatJSON, report.FormatCSV:
+	default:
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be table, markdown, json or csv")
+		fs.Usage()
+		return 2, nil
+	}
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &ReportDurationsConfig{
+		Source:      dir,
+		WorkflowIDs: workflowIDs,
+		Options: report.DurationsOptions{
+			Kind:    k,
+			GroupBy: *groupBy,
+			Filter: export.Filter{
+				From:        start,
+				To:          end,
+				Conclusions: splitList(*conclusion),
+			},
+		},
+		Format: *format,
+		Output: *output,
+	}
+
+	if err := executeReportDurations(config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeReportDurations(config *ReportDurationsConfig, w io.Writer) error {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := config.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	stats, err := report.Durations(store, workflowIDs, config.Options)
+	if err != nil {
+		return err
+	}
+	return writeReport(config.Output, w, func(w io.Writer) error {
+		return report.WriteDurations(w, config.Format, config.Options.GroupBy, stats)
+	})
+}
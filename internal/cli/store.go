@@ -0,0 +1,873 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/elastic"
+	"github.com/teleivo/github-action-metrics/internal/github"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// StoreCheckConfig holds configuration for the store check command.
+type StoreCheckConfig struct {
+	Source     string
+	WorkflowID int64
+	Format     string
+	Fix        bool
+	Repo       string
+	Owner      string
+	Wait       time.Duration
+}
+
+// StorePruneConfig holds configuration for the store prune command.
+type StorePruneConfig struct {
+	Source     string
+	WorkflowID int64
+	Policy     storage.PrunePolicy
+	DryRun     bool
+	Format     string
+	Elastic    elastic.Config
+	Naming     elastic.IndexNaming
+	Wait       time.Duration
+}
+
+// StoreExportConfig holds configuration for the store export command.
+type StoreExportConfig struct {
+	Source  string
+	Output  string
+	Options storage.ExportOptions
+}
+
+// StoreImportConfig holds configuration for the store import command.
+type StoreImportConfig struct {
+	Destination string
+	Input       string
+	Overwrite   bool
+	Wait        time.Duration
+}
+
+// StoreStatsConfig holds configuration for the store stats command.
+type StoreStatsConfig struct {
+	Source      string
+	WorkflowIDs []int64
+	Format      string
+}
+
+// lockStore takes the store lock for the duration of a write command.
+// The returned function releases it.
+func lockStore(ctx context.Context, store *storage.Store, wait time.Duration) (func(), error) {
+	unlock, err := store.Lock(ctx, wait)
+	if err != nil {
+		var lockErr *storage.LockError
+		if errors.As(err, &lockErr) {
+			return nil, fmt.Errorf("%w; use -wait to wait for it to be released", err)
+		}
+		return nil, err
+	}
+	return func() {
+		if err := unlock(); err != nil {
+			slog.Warn("failed to release store lock", "error", err)
+		}
+	}, nil
+}
+
+// int64List is a flag.Value collecting int64 values from repeated or comma-separated flags.
+type int64List []int64
+
+func (l *int64List) String() string {
+	values := make([]string, 0, len(*l))
+	for _, v := range *l {
+		values = append(values, strconv.FormatInt(v, 10))
+	}
+	return strings.Join(values, ",")
+}
+
+func (l *int64List) Set(value string) error {
+	for v := range strings.SplitSeq(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", v)
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
+// parseDateRange parses dates in format '2021-10-12' or '2021-10-29T22:40:19Z'
+// into a half-open range [from, to). A to date without time includes the whole day.
+// Empty values yield a zero time.
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	if from != "" {
+		t, _, err := parseDate(from)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid -from: %w", err)
+		}
+		start = t
+	}
+	if to != "" {
+		t, dateOnly, err := parseDate(to)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid -to: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		end = t
+	}
+	return start, end, nil
+}
+
+func parseDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is not in format '2021-10-12' or '2021-10-29T22:40:19Z'", value)
+	}
+	return t, false, nil
+}
+
+// HandleStore handles the store command and its subcommands.
+func HandleStore(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	if len(args) < 1 {
+		printStoreUsage(wErr)
+		return 2, nil
+	}
+
+	switch args[0] {
+	case "check":
+		return handleStoreCheck(ctx, args[1:], w, wErr)
+	case "prune":
+		return handleStorePrune(ctx, args[1:], w, wErr)
+	case "export":
+		return handleStoreExport(args[1:], w, wErr)
+	case "import":
+		return handleStoreImport(ctx, args[1:], wErr)
+	case "stats":
+		return handleStoreStats(args[1:], w, wErr)
+	default:
+		printStoreUsage(wErr)
+		return 2, nil
+	}
+}
+
+func printStoreUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `Usage: gham store <command> [options]
+
+Commands:
+  check   Check stored runs and jobs for inconsistencies
+  prune   Remove stored runs and jobs according to a retention policy
+  export  Export stored runs and jobs into a single bundle
+  import  Import a bundle into a store
+  stats   Show statistics about stored runs and jobs
+
+Run 'gham store <command> -h' for more information on a command.`)
+}
+
+func handleStoreCheck(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("store check", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham store check [options]
+
+Check stored runs and jobs for inconsistencies such as runs without jobs, jobs
+without a run, unparseable JSON, mismatching IDs and duplicated IDs.
+
+Exits with status 1 if issues were found and not fixed.
+
+With -fix, offending runs and jobs are re-fetched from GitHub or removed. This
+requires -repo, -owner and the GITHUB_TOKEN environment variable. Stored files
+are only replaced once re-fetching succeeded. Duplicated IDs are not fixed
+automatically.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	workflowID := fs.Int64("workflow-id", 0, "Only check this workflow ID (default all workflows)")
+	format := fs.String("format", "text", "Output format: text or json")
+	fix := fs.Bool("fix", false, "Re-fetch or remove offending runs and jobs")
+	repo := fs.String("repo", "", "GitHub repository (required with -fix)")
+	owner := fs.String("owner", "", "Owner of GitHub repository (required with -fix)")
+	wait := fs.Duration("wait", 0, "How long to wait with -fix for another gham process to release the store lock")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "text" && *format != "json" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be text or json")
+		fs.Usage()
+		return 2, nil
+	}
+	if *fix && (*repo == "" || *owner == "") {
+		_, _ = fmt.Fprintln(wErr, "Error: -repo and -owner are required with -fix")
+		fs.Usage()
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &StoreCheckConfig{
+		Source:     dir,
+		WorkflowID: *workflowID,
+		Format:     *format,
+		Fix:        *fix,
+		Repo:       *repo,
+		Owner:      *owner,
+		Wait:       *wait,
+	}
+
+	issues, err := executeStoreCheck(ctx, config, w)
+	if err != nil {
+		return 1, err
+	}
+	if issues > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// executeStoreCheck checks the store and writes the issues found to w.
+// Returns the number of issues that remain after fixing.
+func executeStoreCheck(ctx context.Context, config *StoreCheckConfig, w io.Writer) (int, error) {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return 0, err
+	}
+
+	var workflowIDs []int64
+	if config.WorkflowID != 0 {
+		workflowIDs = append(workflowIDs, config.WorkflowID)
+	}
+
+	issues, err := store.Check(workflowIDs...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeIssues(w, config.Format, issues); err != nil {
+		return 0, err
+	}
+
+	if !config.Fix || len(issues) == 0 {
+		return len(issues), nil
+	}
+
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return len(issues), err
+	}
+	defer unlock()
+
+	client := github.NewClient(getGitHubToken())
+	unfixed := 0
+	fixed := make(map[storage.Issue]bool)
+	for _, issue := range issues {
+		// several issues can point at the same file, fix it only once
+		key := storage.Issue{Kind: fixKind(issue.Kind), WorkflowID: issue.WorkflowID, RunID: issue.RunID}
+		if fixed[key] {
+			continue
+		}
+		fixed[key] = true
+
+		if err := fixIssue(ctx, client, config.Owner, config.Repo, store, issue); err != nil {
+			slog.Warn("failed to fix issue", "kind", issue.Kind, "path", issue.Path, "error", err)
+			unfixed++
+		}
+	}
+	return unfixed, nil
+}
+
+func writeIssues(w io.Writer, format string, issues []storage.Issue) error {
+	if format == "json" {
+		if issues == nil {
+			issues = []storage.Issue{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(issues)
+	}
+
+	for _, issue := range issues {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", issue.Kind, issue.Path, issue.Detail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixKind groups issue kinds that are fixed by the same action.
+func fixKind(kind storage.IssueKind) storage.IssueKind {
+	switch kind {
+	case storage.IssueInvalidJobsJSON, storage.IssueJobsRunIDMismatch:
+		return storage.IssueMissingJobs
+	case storage.IssueRunIDMismatch:
+		return storage.IssueInvalidRunJSON
+	default:
+		return kind
+	}
+}
+
+// fixIssue re-fetches or removes the run or jobs an issue refers to. Stored
+// files are only replaced once re-fetching succeeded.
+func fixIssue(ctx context.Context, client *github.Client, owner, repo string, store *storage.Store, issue storage.Issue) error {
+	switch issue.Kind {
+	case storage.IssueMissingJobs, storage.IssueInvalidJobsJSON, storage.IssueJobsRunIDMismatch:
+		// jobs of a run that is not stored cannot be indexed, so there is no point in re-fetching them
+		if !store.RunExists(issue.WorkflowID, issue.RunID) {
+			slog.Info("removing jobs", "workflow_id", issue.WorkflowID, "run_id", issue.RunID)
+			return store.RemoveJobs(issue.WorkflowID, issue.RunID)
+		}
+		slog.Info("re-fetching jobs", "workflow_id", issue.WorkflowID, "run_id", issue.RunID)
+		return github.FetchRunJobs(ctx, client, owner, repo, issue.WorkflowID, store, issue.RunID)
+	case storage.IssueInvalidRunJSON, storage.IssueRunIDMismatch:
+		slog.Info("re-fetching run", "workflow_id", issue.WorkflowID, "run_id", issue.RunID)
+		return github.FetchRun(ctx, client, owner, repo, issue.WorkflowID, store, issue.RunID)
+	case storage.IssueOrphanJobs:
+		slog.Info("removing jobs", "workflow_id", issue.WorkflowID, "run_id", issue.RunID)
+		return store.RemoveJobs(issue.WorkflowID, issue.RunID)
+	case storage.IssueWorkflowIDMismatch:
+		// the run belongs to another workflow, so are the jobs stored next to it
+		slog.Info("removing run and jobs", "workflow_id", issue.WorkflowID, "run_id", issue.RunID)
+		if err := store.RemoveRun(issue.WorkflowID, issue.RunID); err != nil {
+			return err
+		}
+		return store.RemoveJobs(issue.WorkflowID, issue.RunID)
+	default:
+		return fmt.Errorf("no automatic fix for %s", issue.Kind)
+	}
+}
+
+func handleStorePrune(ctx context.Context, args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("store prune", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham store prune [options]
+
+Remove stored runs and their jobs according to a retention policy. A run is
+removed if any of -keep-days, -keep-runs or -branch selects it. The removed
+runs are listed.
+
+With -url, the runs and their jobs and steps are also deleted from
+Elasticsearch. They are deleted before the stored runs are removed so the
+stored runs are kept if deleting them from Elasticsearch fails.
+
+`+elasticAuthUsage+`
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	workflowID := fs.Int64("workflow-id", 0, "Only prune this workflow ID (default all workflows)")
+	keepDays := fs.Int("keep-days", 0, "Remove runs created more than this many days ago")
+	keepRuns := fs.Int("keep-runs", 0, "Remove all but this many most recent runs per workflow")
+	branch := fs.String("branch", "", "Remove runs whose head branch matches this regular expression")
+	dryRun := fs.Bool("dry-run", false, "List the runs that would be removed without removing them")
+	format := fs.String("format", "text", "Output format: text or json")
+	elasticOpts := addElasticFlags(fs, "Elasticsearch URL to also delete documents of removed runs from")
+	namingOpts := addIndexNamingFlags(fs)
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *keepDays <= 0 && *keepRuns <= 0 && *branch == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: one of -keep-days, -keep-runs or -branch is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "text" && *format != "json" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be text or json")
+		fs.Usage()
+		return 2, nil
+	}
+	elasticConfig := elasticOpts.config()
+	if elasticConfig.URL != "" && !hasElasticAuth(elasticConfig) {
+		_, _ = fmt.Fprintln(wErr, "Error: Elasticsearch authentication is required with -url")
+		_, _ = fmt.Fprintln(wErr, elasticAuthUsage)
+		return 2, nil
+	}
+	naming, err := namingOpts.naming()
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	policy := storage.PrunePolicy{
+		MaxAge:   time.Duration(max(*keepDays, 0)) * 24 * time.Hour,
+		KeepRuns: max(*keepRuns, 0),
+	}
+	if *branch != "" {
+		re, err := regexp.Compile(*branch)
+		if err != nil {
+			_, _ = fmt.Fprintf(wErr, "Error: invalid -branch: %v\n", err)
+			return 2, nil
+		}
+		policy.Branch = re
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &StorePruneConfig{
+		Source:     dir,
+		WorkflowID: *workflowID,
+		Policy:     policy,
+		DryRun:     *dryRun,
+		Format:     *format,
+		Elastic:    elasticConfig,
+		Naming:     naming,
+		Wait:       *wait,
+	}
+
+	if err := executeStorePrune(ctx, config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeStorePrune(ctx context.Context, config *StorePruneConfig, w io.Writer) error {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := []int64{config.WorkflowID}
+	if config.WorkflowID == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	var candidates []storage.PruneCandidate
+	for _, workflowID := range workflowIDs {
+		selected, err := store.SelectPrunable(workflowID, config.Policy)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, selected...)
+	}
+
+	if err := writePruneCandidates(w, config.Format, candidates); err != nil {
+		return err
+	}
+
+	if config.DryRun || len(candidates) == 0 {
+		return nil
+	}
+
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	runIDs := make([]int64, 0, len(candidates))
+	for _, candidate := range candidates {
+		runIDs = append(runIDs, candidate.RunID)
+	}
+
+	// delete the indexed documents first so a failure leaves the runs stored
+	// and pruning can be retried
+	if config.Elastic.URL != "" {
+		client, err := connectElasticsearch(ctx, config.Elastic)
+		if err != nil {
+			return err
+		}
+		if _, err := elastic.DeleteRuns(ctx, client, config.Naming, runIDs); err != nil {
+			return err
+		}
+	}
+
+	removed := make(map[int64][]int64)
+	for _, candidate := range candidates {
+		if err := store.RemoveRunAndJobs(candidate.WorkflowID, candidate.RunID); err != nil {
+			return err
+		}
+		removed[candidate.WorkflowID] = append(removed[candidate.WorkflowID], candidate.RunID)
+	}
+	for workflowID, workflowRunIDs := range removed {
+		if err := store.ForgetIndexedRuns(workflowID, workflowRunIDs); err != nil {
+			return err
+		}
+	}
+	slog.Info("pruned runs", "count", len(candidates))
+	return nil
+}
+
+func writePruneCandidates(w io.Writer, format string, candidates []storage.PruneCandidate) error {
+	if format == "json" {
+		if candidates == nil {
+			candidates = []storage.PruneCandidate{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(candidates)
+	}
+
+	for _, candidate := range candidates {
+		if _, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", candidate.WorkflowID, candidate.RunID, candidate.CreatedAt, candidate.HeadBranch, candidate.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleStoreExport(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("store export", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham store export [options]
+
+Export stored runs and jobs into a single tar.gz or NDJSON bundle starting with
+a manifest. Use 'gham store import' to merge the bundle into another store.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	output := fs.String("output", "", "File to write the bundle to, - for stdout (required)")
+	format := fs.String("format", string(storage.BundleTarGz), "Bundle format: tar.gz or ndjson")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to export, can be repeated (default all workflows)")
+	from := fs.String("from", "", "Only export runs created on or after this date in format '2021-10-12' or '2021-10-29T22:40:19Z'")
+	to := fs.String("to", "", "Only export runs created on or before this day in format '2021-10-12' or before this time in format '2021-10-29T22:40:19Z'")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" || *output == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source and -output are required")
+		fs.Usage()
+		return 2, nil
+	}
+	bundleFormat := storage.BundleFormat(*format)
+	if bundleFormat != storage.BundleTarGz && bundleFormat != storage.BundleNDJSON {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be tar.gz or ndjson")
+		fs.Usage()
+		return 2, nil
+	}
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		_, _ = fmt.Fprintf(wErr, "Error: %v\n", err)
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &StoreExportConfig{
+		Source: dir,
+		Output: *output,
+		Options: storage.ExportOptions{
+			WorkflowIDs: workflowIDs,
+			From:        start,
+			To:          end,
+			Format:      bundleFormat,
+		},
+	}
+
+	if err := executeStoreExport(config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeStoreExport(config *StoreExportConfig, w io.Writer) (err error) {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	if config.Output != "-" {
+		f, cerr := os.Create(config.Output)
+		if cerr != nil {
+			return fmt.Errorf("creating output file: %w", cerr)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing output file: %w", cerr)
+			}
+			// do not leave a partial bundle behind
+			if err != nil {
+				_ = os.Remove(config.Output)
+			}
+		}()
+		w = f
+	}
+
+	manifest, err := store.Export(w, config.Options)
+	if err != nil {
+		return err
+	}
+	for _, workflow := range manifest.Workflows {
+		slog.Info("exported workflow", "workflow_id", workflow.WorkflowID, "runs", workflow.Runs, "jobs", workflow.Jobs)
+	}
+	return nil
+}
+
+func handleStoreImport(ctx context.Context, args []string, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("store import", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham store import [options]
+
+Import a bundle written by 'gham store export' into a store. Runs and jobs that
+are already stored are skipped unless -overwrite is given.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	destination := fs.String("destination", "", "Directory where payloads will be stored (required)")
+	input := fs.String("input", "", "Bundle file to import, - for stdin (required)")
+	overwrite := fs.Bool("overwrite", false, "Overwrite runs and jobs that are already stored")
+	wait := fs.Duration("wait", 0, "How long to wait for another gham process to release the store lock")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *destination == "" || *input == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -destination and -input are required")
+		fs.Usage()
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*destination)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &StoreImportConfig{
+		Destination: dir,
+		Input:       *input,
+		Overwrite:   *overwrite,
+		Wait:        *wait,
+	}
+
+	if err := executeStoreImport(ctx, config); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeStoreImport(ctx context.Context, config *StoreImportConfig) error {
+	store, err := storage.NewStore(config.Destination)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockStore(ctx, store, config.Wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var r io.Reader = os.Stdin
+	if config.Input != "-" {
+		f, err := os.Open(config.Input)
+		if err != nil {
+			return fmt.Errorf("opening bundle: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	result, err := store.Import(r, storage.ImportOptions{Overwrite: config.Overwrite})
+	if err != nil {
+		return err
+	}
+	slog.Info("imported bundle", "runs", result.Runs, "jobs", result.Jobs, "skipped_runs", result.SkippedRuns, "skipped_jobs", result.SkippedJobs)
+	return nil
+}
+
+func handleStoreStats(args []string, w io.Writer, wErr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("store stats", flag.ContinueOnError)
+	fs.SetOutput(wErr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(wErr, `Usage: gham store stats [options]
+
+Show per workflow the number of stored runs and jobs, the time range covered,
+the number of runs without jobs, the run conclusions, the size on disk and the
+time of the last fetch.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	var workflowIDs int64List
+	source := fs.String("source", "", "Directory where GitHub action payloads are stored (required)")
+	fs.Var(&workflowIDs, "workflow-id", "Workflow ID to show, can be repeated (default all workflows)")
+	format := fs.String("format", "table", "Output format: table or json")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, nil
+		}
+		return 2, errFlagParse
+	}
+
+	// Validate required flags
+	if *source == "" {
+		_, _ = fmt.Fprintln(wErr, "Error: -source is required")
+		fs.Usage()
+		return 2, nil
+	}
+	if *format != "table" && *format != "json" {
+		_, _ = fmt.Fprintln(wErr, "Error: -format must be table or json")
+		fs.Usage()
+		return 2, nil
+	}
+
+	dir, err := resolveDirectory(*source)
+	if err != nil {
+		return 1, err
+	}
+
+	config := &StoreStatsConfig{
+		Source:      dir,
+		WorkflowIDs: workflowIDs,
+		Format:      *format,
+	}
+
+	if err := executeStoreStats(config, w); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func executeStoreStats(config *StoreStatsConfig, w io.Writer) error {
+	store, err := storage.NewStore(config.Source)
+	if err != nil {
+		return err
+	}
+
+	workflowIDs := config.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		workflowIDs, err = store.ListWorkflowIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	all := make([]*storage.WorkflowStats, 0, len(workflowIDs))
+	for _, workflowID := range workflowIDs {
+		stats, err := store.Stats(workflowID)
+		if err != nil {
+			return err
+		}
+		all = append(all, stats)
+	}
+
+	if config.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(all)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "WORKFLOW\tRUNS\tJOBS\tMISSING JOBS\tFIRST RUN\tLAST RUN\tCONCLUSIONS\tSIZE\tLAST FETCH")
+	for _, stats := range all {
+		_, _ = fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			stats.WorkflowID,
+			stats.Runs,
+			stats.Jobs,
+			stats.MissingJobs,
+			formatOptionalTime(stats.FirstRunAt),
+			formatOptionalTime(stats.LastRunAt),
+			formatConclusions(stats.Conclusions),
+			formatBytes(stats.Bytes),
+			formatOptionalTime(stats.LastFetchedAt))
+	}
+	return tw.Flush()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatConclusions formats conclusion counts like success=10,failure=2 sorted by conclusion.
+func formatConclusions(conclusions map[string]int) string {
+	if len(conclusions) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(conclusions))
+	for _, conclusion := range slices.Sorted(maps.Keys(conclusions)) {
+		parts = append(parts, conclusion+"="+strconv.Itoa(conclusions[conclusion]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatBytes formats a byte count using binary units like 1.5MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,154 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// aliasIndices returns the indices the alias points to. It returns concrete
+// true if alias is not an alias but an index of that name.
+func (c *Client) aliasIndices(ctx context.Context, alias string) (indices []string, concrete bool, err error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/_alias/"+url.PathEscape(alias), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		concrete, err := c.indexExists(ctx, alias)
+		return nil, concrete, err
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, false, fmt.Errorf("getting alias %q failed with status %d: %s", alias, resp.StatusCode, body)
+	}
+
+	var result map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, false, fmt.Errorf("decoding alias response: %w", err)
+	}
+	return slices.Sorted(maps.Keys(result)), false, nil
+}
+
+// indexExists returns true if an index of the given name exists.
+func (c *Client) indexExists(ctx context.Context, index string) (bool, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, "/"+url.PathEscape(index), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 400:
+		return false, fmt.Errorf("checking index %q failed with status %d", index, resp.StatusCode)
+	}
+	return true, nil
+}
+
+// SwapAliases atomically points each alias to its index in a single request.
+// Indices named like an alias, as created by indexing without aliases, are
+// deleted as part of the swap. Returns the indices the aliases pointed to
+// before.
+func (c *Client) SwapAliases(ctx context.Context, indices map[string]string) ([]string, error) {
+	var actions []map[string]any
+	var old []string
+	for _, alias := range slices.Sorted(maps.Keys(indices)) {
+		index := indices[alias]
+		previous, concrete, err := c.aliasIndices(ctx, alias)
+		if err != nil {
+			return nil, err
+		}
+
+		actions = append(actions, map[string]any{"add": map[string]any{"index": index, "alias": alias}})
+		if concrete {
+			actions = append(actions, map[string]any{"remove_index": map[string]any{"index": alias}})
+		}
+		for _, previous := range previous {
+			if previous == index {
+				continue
+			}
+			actions = append(actions, map[string]any{"remove": map[string]any{"index": previous, "alias": alias}})
+			old = append(old, previous)
+		}
+	}
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(map[string]any{"actions": actions})
+	if err != nil {
+		return nil, fmt.Errorf("encoding alias actions: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/_aliases", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("swapping aliases failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	slog.Info("swapped aliases", "aliases", indices, "previous", old)
+	return old, nil
+}
+
+// DeleteIndices deletes the given indices. Missing indices are ignored.
+func (c *Client) DeleteIndices(ctx context.Context, indices ...string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	escaped := make([]string, len(indices))
+	for i, index := range indices {
+		escaped[i] = url.PathEscape(index)
+	}
+	path := "/" + strings.Join(escaped, ",") + "?ignore_unavailable=true"
+	req, err := c.newRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("deleting indices %v failed with status %d: %s", indices, resp.StatusCode, body)
+	}
+
+	slog.Info("deleted indices", "indices", indices)
+	return nil
+}
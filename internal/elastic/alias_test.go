@@ -0,0 +1,133 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestSwapAliases(t *testing.T) {
+	tests := []struct {
+		name         string
+		aliases      string
+		concrete     bool
+		wantActions  []string
+		wantPrevious []string
+	}{
+		{
+			name:        "no alias or index",
+			wantActions: []string{"add", "add"},
+		},
+		{
+			name:        "replaces index named like alias",
+			concrete:    true,
+			wantActions: []string{"add", "add", "remove_index"},
+		},
+		{
+			name:         "moves alias",
+			aliases:      `{"runs-20211001100000":{"aliases":{"runs":{}}}}`,
+			wantActions:  []string{"add", "add", "remove"},
+			wantPrevious: []string{"runs-20211001100000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotActions []string
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/_alias/jobs":
+					w.WriteHeader(http.StatusNotFound)
+				case r.Method == http.MethodHead && r.URL.Path == "/jobs":
+					w.WriteHeader(http.StatusNotFound)
+				case r.Method == http.MethodGet && r.URL.Path == "/_alias/runs":
+					if tt.aliases == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(tt.aliases))
+				case r.Method == http.MethodHead && r.URL.Path == "/runs":
+					if !tt.concrete {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				case r.Method == http.MethodPost && r.URL.Path == "/_aliases":
+					requests++
+					var body struct {
+						Actions []map[string]any `json:"actions"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("decoding actions: %v", err)
+					}
+					for _, action := range body.Actions {
+						for name := range action {
+							gotActions = append(gotActions, name)
+						}
+					}
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			previous, err := client.SwapAliases(context.Background(), map[string]string{
+				"runs": "runs-20211020100000",
+				"jobs": "jobs-20211020100000",
+			})
+			if err != nil {
+				t.Fatalf("SwapAliases() error = %v", err)
+			}
+			if requests != 1 {
+				t.Errorf("SwapAliases() sent %d alias requests, want 1", requests)
+			}
+			if !slices.Equal(gotActions, tt.wantActions) {
+				t.Errorf("SwapAliases() actions = %v, want %v", gotActions, tt.wantActions)
+			}
+			if !slices.Equal(previous, tt.wantPrevious) {
+				t.Errorf("SwapAliases() = %v, want %v", previous, tt.wantPrevious)
+			}
+		})
+	}
+}
+
+func TestRebuildFailedDocuments(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"created_at":"2021-10-01T10:00:00Z"}`)
+
+	if err := Rebuild(context.Background(), client, store, []int64{10954}, nil); err == nil {
+		t.Fatal("Rebuild() expected error for failed documents")
+	}
+	if len(deleted) != 1 || !strings.HasPrefix(deleted[0], "runs-") {
+		t.Errorf("Rebuild() deleted %v, want the new runs index", deleted)
+	}
+}
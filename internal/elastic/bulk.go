@@ -0,0 +1,267 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for bulk indexing.
+const (
+	DefaultBulkWorkers = 2
+	DefaultBulkActions = 500
+	DefaultBulkBytes   = 5 << 20
+)
+
+// backpressureRetries is the number of times documents the cluster rejects
+// with 429 Too Many Requests are sent again.
+const backpressureRetries = 8
+
+// BulkResult contains statistics from a bulk indexing operation.
+type BulkResult struct {
+	Total      int
+	Successful int
+	Failed     int
+	// FailedIDs are the IDs of the documents that failed to index.
+	FailedIDs []string
+}
+
+// bulkItem is the encoded action and document lines of a document.
+type bulkItem struct {
+	id     string
+	create bool
+	data   []byte
+}
+
+// BulkIndex indexes documents using the Elasticsearch bulk API. Documents
+// are batched by count and size and sent by parallel workers. Documents are
+// indexed into index unless they name their own. Documents the cluster
+// rejects with 429 Too Many Requests are sent again after backing off.
+func (c *Client) BulkIndex(ctx context.Context, index string, docs <-chan Document) (*BulkResult, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var mu sync.Mutex
+	result := &BulkResult{}
+
+	batches := make(chan []bulkItem)
+	var wg sync.WaitGroup
+	for range c.bulkWorkers {
+		wg.Go(func() {
+			for batch := range batches {
+				failed, err := c.sendBatch(ctx, batch)
+				mu.Lock()
+				result.Failed += len(failed)
+				result.FailedIDs = append(result.FailedIDs, failed...)
+				mu.Unlock()
+				if err != nil {
+					cancel(err)
+					return
+				}
+			}
+		})
+	}
+
+	err := c.batch(ctx, index, docs, batches, &result.Total)
+	close(batches)
+	wg.Wait()
+
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		err = cause
+	} else if err == nil {
+		err = ctx.Err()
+	}
+	result.Successful = result.Total - result.Failed
+	return result, err
+}
+
+// batch encodes documents into batches limited by the number of actions and
+// bytes of the client and sends them on batches.
+func (c *Client) batch(ctx context.Context, index string, docs <-chan Document, batches chan<- []bulkItem, total *int) error {
+	var batch []bulkItem
+	var size int
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		batch, size = nil, 0
+		return nil
+	}
+
+	for doc := range docs {
+		item, err := encodeBulkItem(index, doc)
+		if err != nil {
+			return err
+		}
+		*total++
+
+		if size+len(item.data) > c.bulkBytes {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, item)
+		size += len(item.data)
+		if len(batch) >= c.bulkActions {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	return send()
+}
+
+// writeBulk writes the bulk action and document lines of docs to w as
+// BulkIndex would send them.
+func writeBulk(w io.Writer, index string, docs <-chan Document) (*BulkResult, error) {
+	result := &BulkResult{}
+	for doc := range docs {
+		item, err := encodeBulkItem(index, doc)
+		if err != nil {
+			return result, err
+		}
+		if _, err := w.Write(item.data); err != nil {
+			return result, fmt.Errorf("writing document: %w", err)
+		}
+		result.Total++
+	}
+	result.Successful = result.Total
+	return result, nil
+}
+
+// encodeBulkItem encodes the action and document lines of doc.
+func encodeBulkItem(index string, doc Document) (bulkItem, error) {
+	var buf bytes.Buffer
+	docIndex := index
+	if doc.Index != "" {
+		docIndex = doc.Index
+	}
+	op := "index"
+	if doc.Create {
+		op = "create"
+	}
+	action := map[string]any{
+		op: map[string]any{
+			"_index": docIndex,
+			"_id":    doc.ID,
+		},
+	}
+	if err := json.NewEncoder(&buf).Encode(action); err != nil {
+		return bulkItem{}, fmt.Errorf("encoding action: %w", err)
+	}
+	if err := json.NewEncoder(&buf).Encode(doc.Body); err != nil {
+		return bulkItem{}, fmt.Errorf("encoding document: %w", err)
+	}
+	return bulkItem{id: doc.ID, create: doc.Create, data: buf.Bytes()}, nil
+}
+
+// sendBatch sends a batch of documents, backing off and resending documents
+// the cluster rejected due to load. Backoff and Retry-After are honored like
+// for requests failing as a whole. Returns the IDs of the documents that
+// failed to index.
+func (c *Client) sendBatch(ctx context.Context, batch []bulkItem) ([]string, error) {
+	var failed []string
+	for attempt := 0; ; attempt++ {
+		resp, err := c.flush(ctx, batch)
+		if err != nil {
+			return failed, err
+		}
+		failed = append(failed, resp.failed...)
+		if len(resp.rejected) == 0 {
+			return failed, nil
+		}
+		if attempt == backpressureRetries {
+			return failed, fmt.Errorf("cluster rejected %d documents with 429 Too Many Requests after %d retries", len(resp.rejected), backpressureRetries)
+		}
+
+		delay := retryDelayFor(attempt, resp.http)
+		slog.Warn("cluster is overloaded, backing off", "documents", len(resp.rejected), "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return failed, ctx.Err()
+		}
+		batch = resp.rejected
+	}
+}
+
+// bulkResponse is the outcome of a single bulk request.
+type bulkResponse struct {
+	// rejected are the documents rejected with 429 Too Many Requests.
+	rejected []bulkItem
+	// failed are the IDs of the documents that failed to index.
+	failed []string
+	// http is the response to the bulk request. Its body is closed.
+	http *http.Response
+}
+
+func (c *Client) flush(ctx context.Context, batch []bulkItem) (*bulkResponse, error) {
+	var buf bytes.Buffer
+	for _, item := range batch {
+		buf.Write(item.data)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/_bulk", &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bulk request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var body struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding bulk response: %w", err)
+	}
+
+	result := &bulkResponse{http: resp}
+	if !body.Errors {
+		return result, nil
+	}
+	for i, item := range body.Items {
+		for _, action := range item {
+			switch {
+			case action.Status == http.StatusTooManyRequests && i < len(batch):
+				result.rejected = append(result.rejected, batch[i])
+			case action.Status == http.StatusConflict && i < len(batch) && batch[i].create:
+				// the document was created before
+				slog.Debug("document already exists", "id", batch[i].id)
+			case action.Status >= 300:
+				id := ""
+				if i < len(batch) {
+					id = batch[i].id
+				}
+				result.failed = append(result.failed, id)
+				slog.Warn("failed to index document", "id", id, "status", action.Status, "error", string(action.Error))
+			}
+		}
+	}
+	return result, nil
+}
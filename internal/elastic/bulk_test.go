@@ -0,0 +1,161 @@
+package elastic
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkIndex(t *testing.T) {
+	var mu sync.Mutex
+	var requests []int
+	rejected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), `"_index"`) {
+				ids = append(ids, scanner.Text())
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, len(ids))
+
+		var items []string
+		failed := false
+		for _, id := range ids {
+			switch {
+			case strings.Contains(id, `"_id":"3"`) && !rejected:
+				rejected = true
+				failed = true
+				items = append(items, `{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}`)
+			case strings.Contains(id, `"_id":"4"`):
+				failed = true
+				items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}`)
+			default:
+				items = append(items, `{"index":{"status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":` + strconv.FormatBool(failed) + `,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL, BulkWorkers: 2, BulkActions: 2, BulkBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(chan Document)
+	go func() {
+		defer close(docs)
+		for _, id := range []string{"1", "2", "3", "4", "5"} {
+			docs <- Document{ID: id, Body: map[string]any{"id": id}}
+		}
+	}()
+
+	result, err := client.BulkIndex(context.Background(), "runs", docs)
+	if err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	if result.Total != 5 || result.Successful != 4 || result.Failed != 1 {
+		t.Errorf("BulkIndex() = %+v", result)
+	}
+	if !slices.Equal(result.FailedIDs, []string{"4"}) {
+		t.Errorf("BulkIndex() FailedIDs = %v, want [4]", result.FailedIDs)
+	}
+
+	var sent int
+	for _, count := range requests {
+		if count > 2 {
+			t.Errorf("BulkIndex() sent %d documents in one request, want at most 2", count)
+		}
+		sent += count
+	}
+	if sent != 6 {
+		t.Errorf("BulkIndex() sent %d documents, want 6 including the rejected one", sent)
+	}
+}
+
+func TestBulkIndexHonorsRetryAfter(t *testing.T) {
+	var sent []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		sent = append(sent, time.Now())
+		if len(sent) == 1 {
+			w.Header().Set("Retry-After", "1")
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL, BulkWorkers: 1, BulkActions: 1, BulkBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(chan Document, 1)
+	docs <- Document{ID: "1", Body: map[string]any{"id": 1}}
+	close(docs)
+
+	result, err := client.BulkIndex(context.Background(), "runs", docs)
+	if err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	if result.Successful != 1 {
+		t.Errorf("BulkIndex() = %+v", result)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("BulkIndex() sent %d requests, want 2", len(sent))
+	}
+	if waited := sent[1].Sub(sent[0]); waited < time.Second {
+		t.Errorf("BulkIndex() resent rejected document after %s, want at least the Retry-After of 1s", waited)
+	}
+}
+
+func TestBulkIndexBatchesByBytes(t *testing.T) {
+	var requests []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var count int
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), `"_index"`) {
+				count++
+			}
+		}
+		requests = append(requests, count)
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL, BulkWorkers: 1, BulkActions: 100, BulkBytes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(chan Document)
+	go func() {
+		defer close(docs)
+		for range 3 {
+			docs <- Document{ID: "1", Body: map[string]any{"payload": strings.Repeat("x", 100)}}
+		}
+	}()
+
+	if _, err := client.BulkIndex(context.Background(), "jobs", docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	if len(requests) != 3 {
+		t.Errorf("BulkIndex() sent %d requests, want 3", len(requests))
+	}
+}
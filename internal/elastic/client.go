@@ -1,13 +1,17 @@
-// Package elastic provides an Elasticsearch client for bulk indexing.
+// Package elastic provides an Elasticsearch and OpenSearch client for bulk indexing.
 package elastic
 
 import (
-	"bytes"
+	"cmp"
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -15,99 +19,146 @@ const httpTimeout = 60 * time.Second
 
 // Client is an Elasticsearch client for bulk indexing operations.
 type Client struct {
-	baseURL  string
-	username string
-	password string
-	client   *http.Client
+	baseURL     string
+	username    string
+	password    string
+	apiKey      string
+	bearerToken string
+	client      *http.Client
+	info        *ClusterInfo
+	bulkWorkers int
+	bulkActions int
+	bulkBytes   int
+
+	maxRetries   int
+	maxRetryTime time.Duration
+}
+
+// Config holds the settings used to connect to a cluster. Only one
+// authentication method is used, in the order API key, bearer token and
+// basic auth. Client certificates can be combined with any of them.
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	// APIKey is the base64 encoded API key as returned by the create API key API.
+	APIKey      string
+	BearerToken string
+	// CACert is the path to a PEM file of certificate authorities to trust in
+	// addition to the system ones.
+	CACert string
+	// ClientCert and ClientKey are paths to a PEM encoded certificate and key
+	// used for mutual TLS.
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+
+	// BulkWorkers is the number of bulk requests sent in parallel. Defaults
+	// to DefaultBulkWorkers.
+	BulkWorkers int
+	// BulkActions is the maximum number of documents per bulk request.
+	// Defaults to DefaultBulkActions.
+	BulkActions int
+	// BulkBytes is the maximum size of a bulk request body in bytes. A single
+	// larger document is sent on its own. Defaults to DefaultBulkBytes.
+	BulkBytes int
+
+	// MaxRetries is the maximum number of retries of a failed request.
+	// Defaults to DefaultMaxRetries. A negative value disables retries.
+	MaxRetries int
+	// MaxRetryTime bounds the total time spent on a request including
+	// retries. Defaults to DefaultMaxRetryTime.
+	MaxRetryTime time.Duration
 }
 
 // NewClient creates a new Elasticsearch client.
-func NewClient(url, username, password string) *Client {
-	return &Client{
-		baseURL:  url,
-		username: username,
-		password: password,
-		client:   &http.Client{Timeout: httpTimeout},
+func NewClient(config Config) (*Client, error) {
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
 	}
+	return &Client{
+		baseURL:     strings.TrimSuffix(config.URL, "/"),
+		username:    config.Username,
+		password:    config.Password,
+		apiKey:      config.APIKey,
+		bearerToken: config.BearerToken,
+		client:      &http.Client{Timeout: httpTimeout, Transport: transport},
+		bulkWorkers: cmp.Or(config.BulkWorkers, DefaultBulkWorkers),
+		bulkActions: cmp.Or(config.BulkActions, DefaultBulkActions),
+		bulkBytes:   cmp.Or(config.BulkBytes, DefaultBulkBytes),
+
+		maxRetries:   cmp.Or(config.MaxRetries, DefaultMaxRetries),
+		maxRetryTime: cmp.Or(config.MaxRetryTime, DefaultMaxRetryTime),
+	}, nil
 }
 
-// BulkResult contains statistics from a bulk indexing operation.
-type BulkResult struct {
-	Total      int
-	Successful int
-	Failed     int
-}
+// newTransport creates an HTTP transport with the TLS settings of config.
+func newTransport(config Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CACert == "" && config.ClientCert == "" && config.ClientKey == "" && !config.InsecureSkipVerify {
+		return transport, nil
+	}
 
-// BulkIndex indexes documents using the Elasticsearch bulk API.
-// It batches documents and sends them in chunks.
-func (c *Client) BulkIndex(ctx context.Context, index string, docs <-chan Document) (*BulkResult, error) {
-	var buf bytes.Buffer
-	result := &BulkResult{}
-	batchSize := 500
-
-	for doc := range docs {
-		// Action line
-		action := map[string]any{
-			"index": map[string]any{
-				"_index": index,
-				"_id":    doc.ID,
-			},
-		}
-		if err := json.NewEncoder(&buf).Encode(action); err != nil {
-			return result, fmt.Errorf("encoding action: %w", err)
-		}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
 
-		// Document line
-		if err := json.NewEncoder(&buf).Encode(doc.Body); err != nil {
-			return result, fmt.Errorf("encoding document: %w", err)
+	if config.CACert != "" {
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
 		}
-
-		result.Total++
-
-		// Flush batch
-		if result.Total%batchSize == 0 {
-			if err := c.flush(ctx, &buf); err != nil {
-				return result, err
-			}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %q", config.CACert)
 		}
+		tlsConfig.RootCAs = pool
 	}
 
-	// Flush remaining
-	if buf.Len() > 0 {
-		if err := c.flush(ctx, &buf); err != nil {
-			return result, err
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, errors.New("client certificate and key must be given together")
 		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
-	result.Successful = result.Total - result.Failed
-	return result, nil
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
 }
 
-func (c *Client) flush(ctx context.Context, buf *bytes.Buffer) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/_bulk", buf)
+// newRequest creates an authenticated request for the given path.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/x-ndjson")
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("bulk request failed with status %d: %s", resp.StatusCode, body)
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	case c.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
 	}
-
-	buf.Reset()
-	return nil
+	return req, nil
 }
 
 // Document represents a document to be indexed.
 type Document struct {
-	ID   string
-	Body any
+	ID string
+	// Index overrides the index passed to BulkIndex.
+	Index string
+	// Create only indexes the document if it does not exist yet, as required
+	// by data streams.
+	Create bool
+	Body   any
 }
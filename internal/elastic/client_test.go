@@ -0,0 +1,60 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAuthentication(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name:   "basic auth",
+			config: Config{Username: "elastic", Password: "secret"},
+			want:   "Basic ZWxhc3RpYzpzZWNyZXQ=",
+		},
+		{
+			name:   "API key takes precedence",
+			config: Config{Username: "elastic", Password: "secret", APIKey: "a2V5OnZhbHVl"},
+			want:   "ApiKey a2V5OnZhbHVl",
+		},
+		{
+			name:   "bearer token",
+			config: Config{BearerToken: "token"},
+			want:   "Bearer token",
+		},
+		{
+			name:   "no credentials",
+			config: Config{},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(`{"version":{"number":"7.15.1"},"tagline":"You Know, for Search"}`))
+			}))
+			defer server.Close()
+
+			tt.config.URL = server.URL
+			client, err := NewClient(tt.config)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if _, err := client.Connect(context.Background()); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
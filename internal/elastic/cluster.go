@@ -0,0 +1,117 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Distribution is the search engine distribution of a cluster.
+type Distribution string
+
+// Supported distributions.
+const (
+	DistributionElasticsearch Distribution = "elasticsearch"
+	DistributionOpenSearch    Distribution = "opensearch"
+)
+
+// Minimum supported major versions per distribution.
+const (
+	minElasticsearchMajor = 7
+	minOpenSearchMajor    = 2
+)
+
+// ClusterInfo describes the cluster a client is connected to.
+type ClusterInfo struct {
+	Distribution Distribution
+	Version      string
+	Major        int
+}
+
+// parseClusterInfo parses the response of the root endpoint. Elasticsearch
+// identifies itself by its tagline while OpenSearch sets version.distribution.
+func parseClusterInfo(data []byte) (*ClusterInfo, error) {
+	var root struct {
+		Tagline string `json:"tagline"`
+		Version struct {
+			Distribution string `json:"distribution"`
+			Number       string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("decoding cluster info: %w", err)
+	}
+
+	info := &ClusterInfo{Version: root.Version.Number}
+	switch {
+	case root.Version.Distribution == "opensearch":
+		info.Distribution = DistributionOpenSearch
+	case root.Tagline == "You Know, for Search":
+		info.Distribution = DistributionElasticsearch
+	default:
+		return nil, fmt.Errorf("unknown search engine distribution %q with tagline %q", root.Version.Distribution, root.Tagline)
+	}
+
+	major, _, _ := strings.Cut(info.Version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s version %q", info.Distribution, info.Version)
+	}
+	info.Major = n
+	return info, nil
+}
+
+// checkSupported returns an error if the cluster version is not supported.
+func checkSupported(info *ClusterInfo) error {
+	minMajor := minElasticsearchMajor
+	if info.Distribution == DistributionOpenSearch {
+		minMajor = minOpenSearchMajor
+	}
+	if info.Major < minMajor {
+		return fmt.Errorf("%s %s is not supported, version %d.x or newer is required", info.Distribution, info.Version, minMajor)
+	}
+	return nil
+}
+
+// Connect fetches the distribution and version of the cluster from the root
+// endpoint and returns an error if they are not supported. The cluster info
+// is kept in the client for requests that differ between distributions.
+func (c *Client) Connect(ctx context.Context) (*ClusterInfo, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster info: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("cluster info request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	info, err := parseClusterInfo(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSupported(info); err != nil {
+		return nil, err
+	}
+	c.info = info
+	return info, nil
+}
+
+// Info returns the cluster info fetched by Connect or nil if the client is not connected.
+func (c *Client) Info() *ClusterInfo {
+	return c.info
+}
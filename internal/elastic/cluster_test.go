@@ -0,0 +1,82 @@
+package elastic
+
+import (
+	"testing"
+)
+
+func TestParseClusterInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         ClusterInfo
+		wantParseErr bool
+		wantSupport  bool
+	}{
+		{
+			name: "elasticsearch 7",
+			body: `{"name":"es01","cluster_name":"es-docker-cluster","version":{"number":"7.15.1","build_flavor":"default"},"tagline":"You Know, for Search"}`,
+			want: ClusterInfo{
+				Distribution: DistributionElasticsearch,
+				Version:      "7.15.1",
+				Major:        7,
+			},
+			wantSupport: true,
+		},
+		{
+			name: "elasticsearch 6 is not supported",
+			body: `{"version":{"number":"6.8.23"},"tagline":"You Know, for Search"}`,
+			want: ClusterInfo{
+				Distribution: DistributionElasticsearch,
+				Version:      "6.8.23",
+				Major:        6,
+			},
+			wantSupport: false,
+		},
+		{
+			name: "opensearch 2",
+			body: `{"name":"os01","version":{"distribution":"opensearch","number":"2.11.0"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`,
+			want: ClusterInfo{
+				Distribution: DistributionOpenSearch,
+				Version:      "2.11.0",
+				Major:        2,
+			},
+			wantSupport: true,
+		},
+		{
+			name: "opensearch 1 is not supported",
+			body: `{"version":{"distribution":"opensearch","number":"1.3.14"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`,
+			want: ClusterInfo{
+				Distribution: DistributionOpenSearch,
+				Version:      "1.3.14",
+				Major:        1,
+			},
+			wantSupport: false,
+		},
+		{
+			name:         "unknown distribution",
+			body:         `{"version":{"number":"1.0.0"},"tagline":"Something else"}`,
+			wantParseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClusterInfo([]byte(tt.body))
+			if tt.wantParseErr {
+				if err == nil {
+					t.Errorf("parseClusterInfo() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseClusterInfo() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseClusterInfo() = %+v, want %+v", got, tt.want)
+			}
+			if err := checkSupported(got); (err == nil) != tt.wantSupport {
+				t.Errorf("checkSupported() error = %v, want supported %v", err, tt.wantSupport)
+			}
+		})
+	}
+}
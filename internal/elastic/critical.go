@@ -0,0 +1,146 @@
+package elastic
+
+import (
+	"slices"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/workflow"
+)
+
+// CriticalPath is the chain of jobs of a run that determined its duration.
+type CriticalPath struct {
+	// Jobs are the IDs of the jobs on the critical path in execution order.
+	Jobs []int64
+	// Names are the names of the jobs on the critical path in execution order.
+	Names []string
+	// Slack is per job ID how long the job could have taken longer without
+	// delaying the run. Jobs on the critical path have no slack.
+	Slack map[int64]time.Duration
+}
+
+// slack returns the slack of the job and whether it is known.
+func (p *CriticalPath) slack(jobID int64) (time.Duration, bool) {
+	if p == nil {
+		return 0, false
+	}
+	slack, ok := p.Slack[jobID]
+	return slack, ok
+}
+
+// criticalJob is a completed job of a run in the dependency graph.
+type criticalJob struct {
+	job        Job
+	started    time.Time
+	completed  time.Time
+	needs      []*criticalJob
+	dependents []*criticalJob
+}
+
+// ready returns when the job could start as all jobs it needs completed.
+func (j *criticalJob) ready() time.Time {
+	if len(j.needs) == 0 {
+		return j.started
+	}
+	var ready time.Time
+	for _, need := range j.needs {
+		if need.completed.After(ready) {
+			ready = need.completed
+		}
+	}
+	return ready
+}
+
+// ComputeCriticalPath reconstructs the job dependency graph of a run from the
+// needs of the workflow jobs and computes the critical path and slack of each
+// job from the job timestamps. Jobs that cannot be matched to a workflow job
+// are treated as having no dependencies. Returns nil if the workflow is nil or
+// no job completed.
+func ComputeCriticalPath(jobs *JobsResponse, wf *workflow.Workflow) *CriticalPath {
+	if jobs == nil || wf == nil {
+		return nil
+	}
+
+	var nodes []*criticalJob
+	byWorkflowJob := make(map[string][]*criticalJob)
+	workflowJobs := make(map[*criticalJob]*workflow.Job)
+	for _, job := range jobs.Jobs {
+		started, err := time.Parse(time.RFC3339, job.StartedAt)
+		if err != nil {
+			continue
+		}
+		completed, err := time.Parse(time.RFC3339, job.CompletedAt)
+		if err != nil {
+			continue
+		}
+		node := &criticalJob{job: job, started: started, completed: completed}
+		nodes = append(nodes, node)
+		if wfJob := wf.JobFor(job.Name); wfJob != nil {
+			byWorkflowJob[wfJob.ID] = append(byWorkflowJob[wfJob.ID], node)
+			workflowJobs[node] = wfJob
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	for _, node := range nodes {
+		wfJob, ok := workflowJobs[node]
+		if !ok {
+			continue
+		}
+		for _, need := range wfJob.Needs {
+			for _, dependency := range byWorkflowJob[need] {
+				node.needs = append(node.needs, dependency)
+				dependency.dependents = append(dependency.dependents, node)
+			}
+		}
+	}
+
+	// the critical path ends with the last job to complete and follows the
+	// job each job waited for last
+	last := slices.MaxFunc(nodes, func(a, b *criticalJob) int { return a.completed.Compare(b.completed) })
+	var path []*criticalJob
+	for node := last; node != nil; {
+		path = append(path, node)
+		var gate *criticalJob
+		for _, need := range node.needs {
+			if gate == nil || need.completed.After(gate.completed) {
+				gate = need
+			}
+		}
+		node = gate
+	}
+	slices.Reverse(path)
+
+	result := &CriticalPath{Slack: make(map[int64]time.Duration, len(nodes))}
+	for _, node := range path {
+		result.Jobs = append(result.Jobs, node.job.ID)
+		result.Names = append(result.Names, node.job.Name)
+	}
+
+	// the latest a job can complete without delaying the run is the earliest
+	// latest start of the jobs that need it
+	latest := make(map[*criticalJob]time.Time, len(nodes))
+	var latestFinish func(node *criticalJob) time.Time
+	latestFinish = func(node *criticalJob) time.Time {
+		if t, ok := latest[node]; ok {
+			return t
+		}
+		finish := last.completed
+		for _, dependent := range node.dependents {
+			start := latestFinish(dependent).Add(-dependent.completed.Sub(dependent.ready()))
+			if start.Before(finish) {
+				finish = start
+			}
+		}
+		latest[node] = finish
+		return finish
+	}
+	for _, node := range nodes {
+		result.Slack[node.job.ID] = max(latestFinish(node).Sub(node.completed), 0)
+	}
+	for _, node := range path {
+		result.Slack[node.job.ID] = 0
+	}
+	return result
+}
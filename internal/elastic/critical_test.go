@@ -0,0 +1,49 @@
+package elastic
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/workflow"
+)
+
+func TestComputeCriticalPath(t *testing.T) {
+	wf, err := workflow.Parse([]byte(`
+jobs:
+  build: {}
+  lint: {}
+  test:
+    needs: build
+  deploy:
+    needs: [test, lint]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := &JobsResponse{Jobs: []Job{
+		{ID: 1, Name: "build", StartedAt: "2021-10-01T10:00:00Z", CompletedAt: "2021-10-01T10:10:00Z"},
+		{ID: 2, Name: "lint", StartedAt: "2021-10-01T10:00:00Z", CompletedAt: "2021-10-01T10:03:00Z"},
+		{ID: 3, Name: "test (17)", StartedAt: "2021-10-01T10:11:00Z", CompletedAt: "2021-10-01T10:30:00Z"},
+		{ID: 4, Name: "test (21)", StartedAt: "2021-10-01T10:11:00Z", CompletedAt: "2021-10-01T10:20:00Z"},
+		{ID: 5, Name: "deploy", StartedAt: "2021-10-01T10:31:00Z", CompletedAt: "2021-10-01T10:35:00Z"},
+	}}
+
+	got := ComputeCriticalPath(jobs, wf)
+	if got == nil {
+		t.Fatal("ComputeCriticalPath() = nil")
+	}
+	if want := []string{"build", "test (17)", "deploy"}; !slices.Equal(got.Names, want) {
+		t.Errorf("ComputeCriticalPath() names = %v, want %v", got.Names, want)
+	}
+	wantSlack := map[int64]time.Duration{1: 0, 2: 27 * time.Minute, 3: 0, 4: 10 * time.Minute, 5: 0}
+	for id, want := range wantSlack {
+		if got.Slack[id] != want {
+			t.Errorf("ComputeCriticalPath() slack of job %d = %s, want %s", id, got.Slack[id], want)
+		}
+	}
+
+	if ComputeCriticalPath(jobs, nil) != nil {
+		t.Error("ComputeCriticalPath() without workflow expected nil")
+	}
+}
@@ -0,0 +1,273 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// deleteBatchSize limits the number of IDs sent in a single terms query.
+const deleteBatchSize = 1000
+
+// searchPageSize is the number of hits fetched per search request.
+const searchPageSize = 1000
+
+// RunFilter selects indexed runs. Zero fields do not restrict the selection.
+type RunFilter struct {
+	WorkflowID int64
+	// Repository is the full name of the repository like owner/repo.
+	Repository string
+	// From and To restrict the created_at time of runs to [From, To).
+	From time.Time
+	To   time.Time
+}
+
+// IsZero returns true if the filter selects all runs.
+func (f RunFilter) IsZero() bool {
+	return f.WorkflowID == 0 && f.Repository == "" && f.From.IsZero() && f.To.IsZero()
+}
+
+// query returns the query selecting the runs matching the filter.
+func (f RunFilter) query() map[string]any {
+	var filters []any
+	if f.WorkflowID != 0 {
+		filters = append(filters, map[string]any{"term": map[string]any{"workflow_id": f.WorkflowID}})
+	}
+	if f.Repository != "" {
+		// the field is a keyword or a text field with a keyword sub-field depending on the mapping
+		filters = append(filters, map[string]any{"bool": map[string]any{
+			"should": []any{
+				map[string]any{"term": map[string]any{"repository.full_name": f.Repository}},
+				map[string]any{"term": map[string]any{"repository.full_name.keyword": f.Repository}},
+			},
+			"minimum_should_match": 1,
+		}})
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		createdAt := map[string]any{}
+		if !f.From.IsZero() {
+			createdAt["gte"] = f.From.UTC().Format(time.RFC3339)
+		}
+		if !f.To.IsZero() {
+			createdAt["lt"] = f.To.UTC().Format(time.RFC3339)
+		}
+		filters = append(filters, map[string]any{"range": map[string]any{"created_at": createdAt}})
+	}
+	if len(filters) == 0 {
+		return map[string]any{"match_all": map[string]any{}}
+	}
+	return map[string]any{"bool": map[string]any{"filter": filters}}
+}
+
+// FindRunIDs returns the sorted IDs of the indexed runs matching the filter.
+// Runs indexed into more than one index are returned once.
+func FindRunIDs(ctx context.Context, client *Client, naming IndexNaming, filter RunFilter) ([]int64, error) {
+	return findRunIDs(ctx, client, naming, filter, searchPageSize)
+}
+
+// findRunIDs scrolls through the runs matching the filter fetching pageSize
+// hits per request. The scroll keeps a consistent view across all indices so
+// that no run is skipped or returned twice while documents are indexed.
+func findRunIDs(ctx context.Context, client *Client, naming IndexNaming, filter RunFilter, pageSize int) ([]int64, error) {
+	body, err := json.Marshal(map[string]any{
+		"size":    pageSize,
+		"query":   filter.query(),
+		"_source": []string{"id"},
+		"sort":    []string{"_doc"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding search: %w", err)
+	}
+
+	path := "/" + url.PathEscape(naming.Pattern(KindRuns)) + "/_search?scroll=" + scrollKeepAlive + "&ignore_unavailable=true&allow_no_indices=true"
+	page, err := client.search(ctx, path, body)
+	if err != nil {
+		return nil, fmt.Errorf("searching runs: %w", err)
+	}
+
+	scrollID := page.ScrollID
+	defer func() {
+		if err := client.clearScroll(context.WithoutCancel(ctx), scrollID); err != nil {
+			slog.Warn("error clearing scroll", "error", err)
+		}
+	}()
+
+	runIDs := make(map[int64]bool)
+	for len(page.Hits.Hits) > 0 {
+		for _, hit := range page.Hits.Hits {
+			runIDs[hit.Source.ID] = true
+		}
+
+		body, err := json.Marshal(map[string]any{"scroll": scrollKeepAlive, "scroll_id": scrollID})
+		if err != nil {
+			return nil, fmt.Errorf("encoding scroll: %w", err)
+		}
+		page, err = client.search(ctx, "/_search/scroll", body)
+		if err != nil {
+			return nil, fmt.Errorf("scrolling runs: %w", err)
+		}
+		if page.ScrollID != "" {
+			scrollID = page.ScrollID
+		}
+	}
+	return slices.Sorted(maps.Keys(runIDs)), nil
+}
+
+// scrollKeepAlive is how long a scroll is kept between requests.
+const scrollKeepAlive = "1m"
+
+// searchResponse is a page of a search for run IDs.
+type searchResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			Source struct {
+				ID int64 `json:"id"`
+			} `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (c *Client) search(ctx context.Context, path string, body []byte) (*searchResponse, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("search failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding search response: %w", err)
+	}
+	return &result, nil
+}
+
+// clearScroll frees the resources of a scroll. An empty scroll ID is ignored.
+func (c *Client) clearScroll(ctx context.Context, scrollID string) error {
+	if scrollID == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"scroll_id": scrollID})
+	if err != nil {
+		return fmt.Errorf("encoding scroll: %w", err)
+	}
+	req, err := c.newRequest(ctx, http.MethodDelete, "/_search/scroll", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// a scroll that expired already is reported as not found
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("clearing scroll failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// DeleteByQuery deletes all documents in index matching query.
+// Missing indices are ignored. Returns the number of deleted documents.
+func (c *Client) DeleteByQuery(ctx context.Context, index string, query any) (int64, error) {
+	body, err := json.Marshal(map[string]any{"query": query})
+	if err != nil {
+		return 0, fmt.Errorf("encoding query: %w", err)
+	}
+
+	path := "/" + url.PathEscape(index) + "/_delete_by_query?conflicts=proceed&ignore_unavailable=true&refresh=true"
+	req, err := c.newRequest(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("delete by query on %q failed with status %d: %s", index, resp.StatusCode, body)
+	}
+
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decoding delete by query response: %w", err)
+	}
+	return result.Deleted, nil
+}
+
+// DeleteWorkflowDocuments deletes the documents of the given kind of a
+// workflow from the indices named by naming. Returns the number of deleted
+// documents.
+func DeleteWorkflowDocuments(ctx context.Context, client *Client, naming IndexNaming, kind string, workflowID int64) (int64, error) {
+	deleted, err := client.DeleteByQuery(ctx, naming.Pattern(kind), map[string]any{"term": map[string]any{"workflow_id": workflowID}})
+	if err != nil {
+		return 0, fmt.Errorf("deleting %s of workflow %d: %w", kind, workflowID, err)
+	}
+	return deleted, nil
+}
+
+// DeleteResult contains the number of documents deleted per index.
+type DeleteResult struct {
+	Runs  int64 `json:"runs"`
+	Jobs  int64 `json:"jobs"`
+	Steps int64 `json:"steps"`
+}
+
+// DeleteRuns deletes the given runs and their jobs and steps from the indices named by naming.
+func DeleteRuns(ctx context.Context, client *Client, naming IndexNaming, runIDs []int64) (*DeleteResult, error) {
+	result := &DeleteResult{}
+	for start := 0; start < len(runIDs); start += deleteBatchSize {
+		batch := runIDs[start:min(start+deleteBatchSize, len(runIDs))]
+
+		deleted, err := client.DeleteByQuery(ctx, naming.Pattern(KindRuns), map[string]any{"terms": map[string]any{"id": batch}})
+		if err != nil {
+			return result, fmt.Errorf("deleting runs: %w", err)
+		}
+		result.Runs += deleted
+
+		deleted, err = client.DeleteByQuery(ctx, naming.Pattern(KindJobs), map[string]any{"terms": map[string]any{"run_id": batch}})
+		if err != nil {
+			return result, fmt.Errorf("deleting jobs: %w", err)
+		}
+		result.Jobs += deleted
+
+		deleted, err = client.DeleteByQuery(ctx, naming.Pattern(KindSteps), map[string]any{"terms": map[string]any{"run_id": batch}})
+		if err != nil {
+			return result, fmt.Errorf("deleting steps: %w", err)
+		}
+		result.Steps += deleted
+	}
+
+	slog.Info("deleted documents", "runs", result.Runs, "jobs", result.Jobs, "steps", result.Steps)
+	return result, nil
+}
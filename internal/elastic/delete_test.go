@@ -0,0 +1,284 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteByQuery(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"deleted":3}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := client.DeleteByQuery(context.Background(), "runs*", map[string]any{"match_all": map[string]any{}})
+	if err != nil {
+		t.Fatalf("DeleteByQuery() error = %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("DeleteByQuery() = %d, want 3", deleted)
+	}
+	if gotPath != "/runs*/_delete_by_query" {
+		t.Errorf("DeleteByQuery() path = %q", gotPath)
+	}
+	if !strings.Contains(gotQuery, "ignore_unavailable=true") {
+		t.Errorf("DeleteByQuery() query = %q, want missing indices ignored", gotQuery)
+	}
+}
+
+func TestDeleteByQueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"bad query"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteByQuery(context.Background(), "runs", map[string]any{}); err == nil {
+		t.Fatal("DeleteByQuery() expected error")
+	}
+}
+
+func TestDeleteRuns(t *testing.T) {
+	type request struct {
+		index string
+		query map[string]any
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query map[string]any `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding query: %v", err)
+		}
+		index := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_delete_by_query")
+		requests = append(requests, request{index: index, query: body.Query})
+		_, _ = w.Write([]byte(`{"deleted":2}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := DeleteRuns(context.Background(), client, IndexNaming{Prefix: "gham-"}, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("DeleteRuns() error = %v", err)
+	}
+	if *result != (DeleteResult{Runs: 2, Jobs: 2, Steps: 2}) {
+		t.Errorf("DeleteRuns() = %+v", result)
+	}
+
+	ids := []any{float64(1), float64(2)}
+	want := []request{
+		{index: "gham-runs", query: map[string]any{"terms": map[string]any{"id": ids}}},
+		{index: "gham-jobs", query: map[string]any{"terms": map[string]any{"run_id": ids}}},
+		{index: "gham-steps", query: map[string]any{"terms": map[string]any{"run_id": ids}}},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("DeleteRuns() requests = %v, want %v", requests, want)
+	}
+}
+
+func TestDeleteWorkflowDocuments(t *testing.T) {
+	var gotPath string
+	var gotQuery map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		var body struct {
+			Query map[string]any `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding query: %v", err)
+		}
+		gotQuery = body.Query
+		_, _ = w.Write([]byte(`{"deleted":4}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	naming := IndexNaming{Template: "{kind}-{workflow_id}"}
+	deleted, err := DeleteWorkflowDocuments(context.Background(), client, naming, KindActions, 10954)
+	if err != nil {
+		t.Fatalf("DeleteWorkflowDocuments() error = %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("DeleteWorkflowDocuments() = %d, want 4", deleted)
+	}
+	if gotPath != "/actions-*/_delete_by_query" {
+		t.Errorf("DeleteWorkflowDocuments() path = %q", gotPath)
+	}
+	want := map[string]any{"term": map[string]any{"workflow_id": float64(10954)}}
+	if !reflect.DeepEqual(gotQuery, want) {
+		t.Errorf("DeleteWorkflowDocuments() query = %v, want %v", gotQuery, want)
+	}
+}
+
+func TestRunFilterQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter RunFilter
+		want   string
+	}{
+		{
+			name: "all runs",
+			want: `{"match_all":{}}`,
+		},
+		{
+			name:   "workflow",
+			filter: RunFilter{WorkflowID: 10954},
+			want:   `{"bool":{"filter":[{"term":{"workflow_id":10954}}]}}`,
+		},
+		{
+			name:   "repository",
+			filter: RunFilter{Repository: "dhis2/dhis2-core"},
+			want:   `{"bool":{"filter":[{"bool":{"minimum_should_match":1,"should":[{"term":{"repository.full_name":"dhis2/dhis2-core"}},{"term":{"repository.full_name.keyword":"dhis2/dhis2-core"}}]}}]}}`,
+		},
+		{
+			name: "date range",
+			filter: RunFilter{
+				From: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: `{"bool":{"filter":[{"range":{"created_at":{"gte":"2021-10-01T00:00:00Z","lt":"2021-11-01T00:00:00Z"}}}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery json.RawMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Query json.RawMessage `json:"query"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding search: %v", err)
+				}
+				gotQuery = body.Query
+				_, _ = w.Write([]byte(`{"hits":{"hits":[]}}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := FindRunIDs(context.Background(), client, IndexNaming{}, tt.filter); err != nil {
+				t.Fatalf("FindRunIDs() error = %v", err)
+			}
+			if string(gotQuery) != tt.want {
+				t.Errorf("FindRunIDs() query = %s, want %s", gotQuery, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRunIDs(t *testing.T) {
+	tests := []struct {
+		name         string
+		runs         []int64
+		want         []int64
+		wantRequests int
+	}{
+		{
+			name:         "no runs",
+			wantRequests: 1,
+		},
+		{
+			name:         "several pages",
+			runs:         []int64{3, 1, 2},
+			want:         []int64{1, 2, 3},
+			wantRequests: 3,
+		},
+		{
+			name:         "runs in several indices",
+			runs:         []int64{2, 1, 2, 3, 1},
+			want:         []int64{1, 2, 3},
+			wantRequests: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests, next int
+			var cleared []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Size     int    `json:"size"`
+					Sort     []any  `json:"sort"`
+					ScrollID string `json:"scroll_id"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+
+				switch {
+				case r.Method == http.MethodDelete && r.URL.Path == "/_search/scroll":
+					cleared = append(cleared, body.ScrollID)
+					return
+				case r.URL.Path == "/runs/_search":
+					if r.URL.Query().Get("scroll") == "" {
+						t.Errorf("FindRunIDs() searched without a scroll: %s", r.URL)
+					}
+					if !slices.Equal(body.Sort, []any{"_doc"}) {
+						t.Errorf("FindRunIDs() sort = %v, want [_doc]", body.Sort)
+					}
+				case r.URL.Path == "/_search/scroll":
+					if body.ScrollID != "scroll-1" {
+						t.Errorf("FindRunIDs() scroll_id = %q, want scroll-1", body.ScrollID)
+					}
+				default:
+					t.Errorf("FindRunIDs() unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				requests++
+
+				var hits []string
+				for ; next < len(tt.runs) && len(hits) < 2; next++ {
+					hits = append(hits, fmt.Sprintf(`{"_source":{"id":%d}}`, tt.runs[next]))
+				}
+				_, _ = w.Write([]byte(`{"_scroll_id":"scroll-1","hits":{"hits":[` + strings.Join(hits, ",") + `]}}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			runIDs, err := findRunIDs(context.Background(), client, IndexNaming{}, RunFilter{}, 2)
+			if err != nil {
+				t.Fatalf("FindRunIDs() error = %v", err)
+			}
+			if !slices.Equal(runIDs, tt.want) {
+				t.Errorf("FindRunIDs() = %v, want %v", runIDs, tt.want)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("FindRunIDs() sent %d search requests, want %d", requests, tt.wantRequests)
+			}
+			if !slices.Equal(cleared, []string{"scroll-1"}) {
+				t.Errorf("FindRunIDs() cleared scrolls %v, want [scroll-1]", cleared)
+			}
+		})
+	}
+}
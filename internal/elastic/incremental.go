@@ -0,0 +1,200 @@
+package elastic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// indexTracker filters documents to those of runs whose stored files changed
+// since they were last indexed into the same cluster and indices. It records
+// the fingerprints of the files documents are indexed from.
+type indexTracker struct {
+	store      *storage.Store
+	target     string
+	workflowID int64
+	kind       string
+	// disabled includes all documents without loading or saving the state.
+	disabled bool
+	// dryRun filters documents without saving the state.
+	dryRun bool
+	full   bool
+	// runners are the stored snapshots of self-hosted runners job and step
+	// documents are classified by.
+	runners []string
+
+	state storage.IndexState
+
+	mu        sync.Mutex
+	current   map[int64]string
+	changed   map[int64]bool
+	unchanged int
+	// runs maps the IDs of included documents to their run.
+	runs map[string]int64
+	// failed are the runs with documents that failed to index.
+	failed map[int64]bool
+}
+
+// newIndexTracker loads the index state of the workflow. Rebuilds write into
+// new indices so they send all documents and leave the state untouched. Dry
+// runs without a client write all documents and dry runs never save the state.
+func newIndexTracker(client *Client, store *storage.Store, kind string, workflowID int64, opts *IndexOptions) (*indexTracker, error) {
+	tracker := &indexTracker{
+		store:      store,
+		workflowID: workflowID,
+		kind:       kind,
+		disabled:   opts.rebuild != nil || client == nil,
+		dryRun:     opts.DryRun != nil,
+		full:       opts.Full,
+		current:    make(map[int64]string),
+		changed:    make(map[int64]bool),
+		runs:       make(map[string]int64),
+		failed:     make(map[int64]bool),
+	}
+	if tracker.disabled {
+		return tracker, nil
+	}
+
+	tracker.target = indexTarget(client, opts)
+	state, err := store.LoadIndexState(tracker.target, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	tracker.state = state
+
+	if kind != KindRuns {
+		tracker.runners, err = store.RunnersPaths()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tracker, nil
+}
+
+// indexTarget identifies the cluster and indices or data streams documents
+// are indexed into and the options they are built with, so that changing
+// either indexes all documents again.
+func indexTarget(client *Client, opts *IndexOptions) string {
+	naming := opts.Naming
+	target := client.baseURL + "\x00" + naming.Prefix + "\x00" + naming.template() + "\x00" + naming.Owner + "\x00" + naming.Repo
+	if opts.DataStreams {
+		target += "\x00data-streams"
+	}
+	if fingerprint := opts.Documents.fingerprint(); fingerprint != "" {
+		target += "\x00" + fingerprint
+	}
+	hash := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(hash[:8])
+}
+
+// include returns true if the document with the given ID belongs to a run
+// whose files changed since it was last indexed. Documents whose run cannot
+// be determined are always included.
+func (t *indexTracker) include(id string, body map[string]any) bool {
+	if t.disabled {
+		return true
+	}
+
+	field := "run_id"
+	if t.kind == KindRuns {
+		field = "id"
+	}
+	value, ok := body[field].(float64)
+	if !ok {
+		return true
+	}
+	runID := int64(value)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.runs[id] = runID
+	if changed, ok := t.changed[runID]; ok {
+		if !changed {
+			t.unchanged++
+		}
+		return changed
+	}
+
+	headSHA, _ := body["head_sha"].(string)
+	fingerprint, err := storage.Fingerprint(t.sources(runID, headSHA)...)
+	if err != nil {
+		t.changed[runID] = true
+		return true
+	}
+	t.current[runID] = fingerprint
+	changed := t.full || t.state[t.kind][runID] != fingerprint
+	t.changed[runID] = changed
+	if !changed {
+		t.unchanged++
+	}
+	return changed
+}
+
+// sources returns the stored files the documents of a run are built from.
+// Runs are enriched with their jobs and jobs and steps with their run. The
+// workflow file version of the commit the run ran on is referenced by all.
+// Jobs and steps are also classified by the snapshots of self-hosted runners.
+func (t *indexTracker) sources(runID int64, headSHA string) []string {
+	sources := []string{t.store.RunPath(t.workflowID, runID), t.store.JobPath(t.workflowID, runID)}
+	if headSHA != "" {
+		sources = append(sources, t.store.WorkflowFileRefPath(t.workflowID, headSHA))
+	}
+	return append(sources, t.runners...)
+}
+
+// fail marks the runs of the documents with the given IDs as failed. Their
+// fingerprints are dropped on save so they are indexed again.
+func (t *indexTracker) fail(ids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range ids {
+		if runID, ok := t.runs[id]; ok {
+			t.failed[runID] = true
+		}
+	}
+}
+
+// save records the fingerprints of the indexed runs. Runs no longer stored or
+// with documents that failed to index are dropped so they are indexed again.
+func (t *indexTracker) save() error {
+	if t.disabled || t.dryRun {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var stored []int64
+	var err error
+	if t.kind == KindRuns {
+		stored, err = t.store.ListStoredRunIDs(t.workflowID)
+	} else {
+		stored, err = t.store.ListStoredJobRunIDs(t.workflowID)
+	}
+	if err != nil {
+		return err
+	}
+
+	fingerprints := make(map[int64]string, len(stored))
+	for _, runID := range stored {
+		if t.failed[runID] {
+			continue
+		}
+		if fingerprint, ok := t.current[runID]; ok {
+			fingerprints[runID] = fingerprint
+		} else if fingerprint, ok := t.state[t.kind][runID]; ok {
+			fingerprints[runID] = fingerprint
+		}
+	}
+	t.state[t.kind] = fingerprints
+
+	if err := t.store.SaveIndexState(t.target, t.workflowID, t.state); err != nil {
+		return fmt.Errorf("saving index state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package elastic
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/teleivo/github-action-metrics/internal/billing"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+	"github.com/teleivo/github-action-metrics/internal/workflow"
+)
+
+func TestIndexIncremental(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), `"_index"`) {
+				sent++
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"created_at":"2021-10-01T10:00:00Z"}`)
+	mustSaveRun(t, store, 2, `{"id":2,"created_at":"2021-10-02T10:00:00Z"}`)
+
+	index := func(opts *IndexOptions) int {
+		t.Helper()
+		sent = 0
+		if _, err := IndexRuns(context.Background(), client, store, 10954, opts); err != nil {
+			t.Fatalf("IndexRuns() error = %v", err)
+		}
+		return sent
+	}
+
+	if got := index(nil); got != 2 {
+		t.Errorf("first IndexRuns() sent %d documents, want 2", got)
+	}
+	if got := index(nil); got != 0 {
+		t.Errorf("IndexRuns() without changes sent %d documents, want 0", got)
+	}
+
+	mustSaveRun(t, store, 2, `{"id":2,"created_at":"2021-10-02T10:00:00Z","conclusion":"success"}`)
+	mustSaveRun(t, store, 3, `{"id":3,"created_at":"2021-10-03T10:00:00Z"}`)
+	if got := index(nil); got != 2 {
+		t.Errorf("IndexRuns() after changes sent %d documents, want 2", got)
+	}
+
+	if got := index(&IndexOptions{Full: true}); got != 3 {
+		t.Errorf("IndexRuns() with full sent %d documents, want 3", got)
+	}
+	if got := index(&IndexOptions{Naming: IndexNaming{Prefix: "other-"}}); got != 3 {
+		t.Errorf("IndexRuns() into other indices sent %d documents, want 3", got)
+	}
+	if got := index(&IndexOptions{DataStreams: true}); got != 3 {
+		t.Errorf("IndexRuns() into data streams sent %d documents, want 3", got)
+	}
+	if got := index(&IndexOptions{DataStreams: true}); got != 0 {
+		t.Errorf("IndexRuns() into unchanged data streams sent %d documents, want 0", got)
+	}
+
+	priced := &IndexOptions{Documents: DocumentOptions{Prices: billing.PriceTable{"linux-standard": 0.006}}}
+	if got := index(priced); got != 3 {
+		t.Errorf("IndexRuns() with other prices sent %d documents, want 3", got)
+	}
+	if got := index(priced); got != 0 {
+		t.Errorf("IndexRuns() with unchanged prices sent %d documents, want 0", got)
+	}
+	workflowFile := &IndexOptions{Documents: DocumentOptions{Workflow: &workflow.Workflow{Name: "CI"}}}
+	if got := index(workflowFile); got != 3 {
+		t.Errorf("IndexRuns() with a workflow file sent %d documents, want 3", got)
+	}
+}
+
+func TestIndexIncrementalRunners(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), `"_index"`) {
+				sent++
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"created_at":"2021-10-01T10:00:00Z"}`)
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[{"id":7,"run_id":1,"runner_name":"build-01","labels":["build"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func() int {
+		t.Helper()
+		sent = 0
+		if _, err := IndexJobs(context.Background(), client, store, 10954, nil); err != nil {
+			t.Fatalf("IndexJobs() error = %v", err)
+		}
+		return sent
+	}
+
+	if got := index(); got != 1 {
+		t.Errorf("first IndexJobs() sent %d documents, want 1", got)
+	}
+	if err := store.SaveRunners("dhis2", "", []byte(`{"runners":[{"name":"build-01","os":"Linux"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := index(); got != 1 {
+		t.Errorf("IndexJobs() after fetching runners sent %d documents, want 1", got)
+	}
+	if got := index(); got != 0 {
+		t.Errorf("IndexJobs() without changes sent %d documents, want 0", got)
+	}
+}
+
+func TestIndexIncrementalRunFields(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), `"_index"`) {
+				sent++
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"created_at":"2021-10-01T10:00:00Z","event":"push"}`)
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[{"id":7,"run_id":1}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(runFields []string) int {
+		t.Helper()
+		sent = 0
+		opts := &IndexOptions{Documents: DocumentOptions{RunFields: runFields}}
+		if _, err := IndexJobs(context.Background(), client, store, 10954, opts); err != nil {
+			t.Fatalf("IndexJobs() error = %v", err)
+		}
+		return sent
+	}
+
+	if got := index(nil); got != 1 {
+		t.Errorf("first IndexJobs() sent %d documents, want 1", got)
+	}
+	if got := index([]string{"event"}); got != 1 {
+		t.Errorf("IndexJobs() with other run fields sent %d documents, want 1", got)
+	}
+	if got := index([]string{"event"}); got != 0 {
+		t.Errorf("IndexJobs() with unchanged run fields sent %d documents, want 0", got)
+	}
+	reordered := slices.Clone(DefaultRunFields)
+	slices.Reverse(reordered)
+	if got := index(reordered); got != 0 {
+		t.Errorf("IndexJobs() with reordered default run fields sent %d documents, want 0", got)
+	}
+}
+
+func TestIndexIncrementalFailedRuns(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items []string
+		failed := false
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.Contains(line, `"_index"`) {
+				continue
+			}
+			sent = append(sent, line)
+			if strings.Contains(line, `"_id":"2"`) {
+				failed = true
+				items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}`)
+			} else {
+				items = append(items, `{"index":{"status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":` + strconv.FormatBool(failed) + `,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"created_at":"2021-10-01T10:00:00Z"}`)
+	mustSaveRun(t, store, 2, `{"id":2,"created_at":"2021-10-02T10:00:00Z"}`)
+
+	for i, want := range []int{2, 1, 1} {
+		sent = nil
+		result, err := IndexRuns(context.Background(), client, store, 10954, nil)
+		if err != nil {
+			t.Fatalf("IndexRuns() error = %v", err)
+		}
+		if len(sent) != want {
+			t.Errorf("IndexRuns() #%d sent %d documents, want %d", i+1, len(sent), want)
+		}
+		if result.Failed != 1 {
+			t.Errorf("IndexRuns() #%d failed %d documents, want 1", i+1, result.Failed)
+		}
+	}
+}
+
+func mustSaveRun(t *testing.T, store *storage.Store, runID int64, data string) {
+	t.Helper()
+	if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,21 +2,31 @@ package elastic
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"iter"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/teleivo/github-action-metrics/internal/billing"
 	"github.com/teleivo/github-action-metrics/internal/storage"
+	"github.com/teleivo/github-action-metrics/internal/workflow"
 )
 
-// IndexRuns indexes workflow runs into Elasticsearch.
-func IndexRuns(ctx context.Context, client *Client, store *storage.Store, workflowID int64) (*BulkResult, error) {
-	docs := make(chan Document)
-
-	go func() {
-		defer close(docs)
+// RunDocuments returns the run documents of a workflow, enriched with the
+// duration and critical path of their jobs and the hash of the workflow file
+// version they ran.
+func RunDocuments(store *storage.Store, workflowID int64, opts *DocumentOptions) iter.Seq[Document] {
+	return func(yield func(Document) bool) {
+		versions := newWorkflowVersions(store, workflowID, opts.workflow())
 		for data, err := range store.IterRuns(workflowID) {
 			if err != nil {
 				slog.Warn("error reading run", "error", err)
@@ -35,6 +45,11 @@ func IndexRuns(ctx context.Context, client *Client, store *storage.Store, workfl
 				continue
 			}
 
+			headSHA, _ := run["head_sha"].(string)
+			if hash := versions.hash(headSHA); hash != "" {
+				run["workflow_file_hash"] = hash
+			}
+
 			// Load jobs and compute duration
 			jobsData, err := store.LoadJobs(workflowID, int64(runID))
 			if err == nil {
@@ -52,31 +67,229 @@ func IndexRuns(ctx context.Context, client *Client, store *storage.Store, workfl
 						run["jobs_completed_at_url"] = duration.JobsCompletedAtURL
 						run["jobs_completed_at_html_url"] = duration.JobsCompletedAtHTMLURL
 					}
+					if path := ComputeCriticalPath(&jobs, versions.workflow(headSHA)); path != nil {
+						run["critical_path"] = path.Names
+						run["critical_path_job_ids"] = path.Jobs
+					}
 				}
 			}
 
-			docs <- Document{
+			if !yield(Document{
 				ID:   strconv.FormatInt(int64(runID), 10),
 				Body: run,
+			}) {
+				return
 			}
 		}
-	}()
+	}
+}
+
+// DefaultRunFields are the run fields denormalized into job and step documents.
+var DefaultRunFields = []string{"event", "head_branch", "actor.login", "pull_requests.number", "conclusion", "name", "workflow_id"}
 
-	result, err := client.BulkIndex(ctx, "runs", docs)
+// DocumentOptions configures how documents are built from the stored runs and jobs.
+type DocumentOptions struct {
+	// RunFields are the run fields denormalized into job and step documents.
+	// Nil uses DefaultRunFields.
+	RunFields []string
+	// Workflow is the workflow definition used to compute the critical path
+	// of runs. If it is nil the workflow file stored for the commit of each
+	// run is used. The critical path is not computed for runs without either.
+	Workflow *workflow.Workflow
+	// Prices are used to estimate the cost of jobs. Nil uses billing.DefaultPrices.
+	Prices billing.PriceTable
+}
+
+// runFields returns the run fields to denormalize into job and step documents.
+func (o *DocumentOptions) runFields() []string {
+	if o == nil || o.RunFields == nil {
+		return DefaultRunFields
+	}
+	return o.RunFields
+}
+
+// prices returns the price table to estimate the cost of jobs.
+func (o *DocumentOptions) prices() billing.PriceTable {
+	if o == nil || o.Prices == nil {
+		return billing.DefaultPrices
+	}
+	return o.Prices
+}
+
+// fingerprint returns a hash of the run fields, prices and workflow
+// definition documents are built with. It is empty if all are the defaults
+// so they do not change the index target.
+func (o *DocumentOptions) fingerprint() string {
+	runFields := slices.Sorted(slices.Values(o.runFields()))
+	if o == nil || (o.Prices == nil && o.Workflow == nil && slices.Equal(runFields, slices.Sorted(slices.Values(DefaultRunFields)))) {
+		return ""
+	}
+	data, err := json.Marshal(struct {
+		RunFields []string
+		Prices    billing.PriceTable
+		Workflow  *workflow.Workflow
+	}{runFields, o.Prices, o.Workflow})
 	if err != nil {
-		return result, fmt.Errorf("indexing runs: %w", err)
+		slog.Warn("error encoding document options", "error", err)
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// workflow returns the workflow definition overriding stored workflow files or nil.
+func (o *DocumentOptions) workflow() *workflow.Workflow {
+	if o == nil {
+		return nil
 	}
+	return o.Workflow
+}
 
-	slog.Info("indexed runs", "total", result.Total, "successful", result.Successful, "failed", result.Failed)
-	return result, nil
+// workflowVersions resolves the stored workflow file version of runs by the
+// commit they ran on. Parsed versions are cached by content hash.
+type workflowVersions struct {
+	store      *storage.Store
+	workflowID int64
+	override   *workflow.Workflow
+	parsed     map[string]*workflow.Workflow
 }
 
-// IndexJobs indexes workflow jobs into Elasticsearch.
-func IndexJobs(ctx context.Context, client *Client, store *storage.Store, workflowID int64) (*BulkResult, error) {
-	docs := make(chan Document)
+func newWorkflowVersions(store *storage.Store, workflowID int64, override *workflow.Workflow) *workflowVersions {
+	return &workflowVersions{
+		store:      store,
+		workflowID: workflowID,
+		override:   override,
+		parsed:     make(map[string]*workflow.Workflow),
+	}
+}
 
-	go func() {
-		defer close(docs)
+// hash returns the content hash of the workflow file stored for the commit
+// or an empty string if none is stored.
+func (v *workflowVersions) hash(headSHA string) string {
+	if headSHA == "" {
+		return ""
+	}
+	hash, err := v.store.WorkflowFileHash(v.workflowID, headSHA)
+	if err != nil {
+		slog.Warn("error reading workflow file hash", "head_sha", headSHA, "error", err)
+		return ""
+	}
+	return hash
+}
+
+// workflow returns the override or else the parsed workflow file stored for
+// the commit. It returns nil if neither is available or the file is invalid.
+func (v *workflowVersions) workflow(headSHA string) *workflow.Workflow {
+	if v.override != nil {
+		return v.override
+	}
+	hash := v.hash(headSHA)
+	if hash == "" {
+		return nil
+	}
+	if wf, ok := v.parsed[hash]; ok {
+		return wf
+	}
+
+	var wf *workflow.Workflow
+	data, err := v.store.LoadWorkflowFile(hash)
+	if err == nil {
+		wf, err = workflow.Parse(data)
+	}
+	if err != nil {
+		slog.Warn("error loading workflow file", "hash", hash, "error", err)
+	}
+	v.parsed[hash] = wf
+	return wf
+}
+
+// headSHA returns the commit the jobs of a run ran on.
+func headSHA(jobs []map[string]any) string {
+	if len(jobs) == 0 {
+		return ""
+	}
+	sha, _ := jobs[0]["head_sha"].(string)
+	return sha
+}
+
+// runContext returns the values of fields of the run of the given jobs keyed
+// by run_ followed by the field path with dots replaced by underscores. Values
+// of fields within arrays are collected into arrays. Missing fields are omitted.
+func runContext(store *storage.Store, workflowID int64, jobs []map[string]any, fields []string) map[string]any {
+	if len(fields) == 0 || len(jobs) == 0 {
+		return nil
+	}
+	runID, ok := jobs[0]["run_id"].(float64)
+	if !ok {
+		return nil
+	}
+	data, err := store.LoadRun(workflowID, int64(runID))
+	if err != nil {
+		slog.Warn("error loading run of jobs", "run_id", int64(runID), "error", err)
+		return nil
+	}
+	var run map[string]any
+	if err := json.Unmarshal(data, &run); err != nil {
+		slog.Warn("error unmarshaling run", "run_id", int64(runID), "error", err)
+		return nil
+	}
+
+	values := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if value, ok := lookupField(run, strings.Split(field, ".")); ok {
+			values["run_"+strings.ReplaceAll(field, ".", "_")] = value
+		}
+	}
+	return values
+}
+
+// lookupField returns the value at path in value. Arrays along the path
+// yield an array of the values of their elements.
+func lookupField(value any, path []string) (any, bool) {
+	if len(path) == 0 {
+		return value, value != nil
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return lookupField(v[path[0]], path[1:])
+	case []any:
+		values := []any{}
+		for _, element := range v {
+			if found, ok := lookupField(element, path); ok {
+				values = append(values, found)
+			}
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// addMatrixFields adds the base name of a job without matrix values and its
+// matrix values to doc. The fields are prefixed by prefix. If the workflow is
+// given only names of its matrix jobs are split and the matrix values are
+// mapped to the matrix keys.
+func addMatrixFields(doc map[string]any, prefix, jobName string, wf *workflow.Workflow) {
+	base, values := wf.SplitName(jobName)
+	doc[prefix+"base_name"] = base
+	if values == nil {
+		return
+	}
+	doc[prefix+"matrix_values"] = values
+	if wf == nil {
+		return
+	}
+	if matrix := wf.Matrix(jobName); matrix != nil {
+		doc[prefix+"matrix"] = matrix
+	}
+}
+
+// JobDocuments returns the job documents of a workflow, enriched with fields
+// of their run, their slack towards the critical path of the run, their base
+// name and matrix values and the class and cost of their runner.
+func JobDocuments(store *storage.Store, workflowID int64, opts *DocumentOptions) iter.Seq[Document] {
+	return func(yield func(Document) bool) {
+		versions := newWorkflowVersions(store, workflowID, opts.workflow())
+		runners := loadRunners(store)
 		for data, err := range store.IterJobs(workflowID) {
 			if err != nil {
 				slog.Warn("error reading jobs", "error", err)
@@ -91,34 +304,48 @@ func IndexJobs(ctx context.Context, client *Client, store *storage.Store, workfl
 				continue
 			}
 
+			run := runContext(store, workflowID, jobsResp.Jobs, opts.runFields())
+			var path *CriticalPath
+			wf := versions.workflow(headSHA(jobsResp.Jobs))
+			if wf != nil {
+				var jobs JobsResponse
+				if err := json.Unmarshal(data, &jobs); err == nil {
+					path = ComputeCriticalPath(&jobs, wf)
+				}
+			}
 			for _, job := range jobsResp.Jobs {
 				jobID, ok := job["id"].(float64)
 				if !ok {
 					continue
 				}
-				docs <- Document{
+				maps.Copy(job, run)
+				jobName, _ := job["name"].(string)
+				addMatrixFields(job, "", jobName, wf)
+				runner := classifyRunner(job, runners)
+				runner.addTo(job)
+				addCost(job, runner, opts.prices())
+				if slack, ok := path.slack(int64(jobID)); ok {
+					job["critical_path"] = slack == 0
+					job["slack_ms"] = slack.Milliseconds()
+				}
+				if !yield(Document{
 					ID:   strconv.FormatInt(int64(jobID), 10),
 					Body: job,
+				}) {
+					return
 				}
 			}
 		}
-	}()
-
-	result, err := client.BulkIndex(ctx, "jobs", docs)
-	if err != nil {
-		return result, fmt.Errorf("indexing jobs: %w", err)
 	}
-
-	slog.Info("indexed jobs", "total", result.Total, "successful", result.Successful, "failed", result.Failed)
-	return result, nil
 }
 
-// IndexSteps indexes workflow steps into Elasticsearch.
-func IndexSteps(ctx context.Context, client *Client, store *storage.Store, workflowID int64) (*BulkResult, error) {
-	docs := make(chan Document)
-
-	go func() {
-		defer close(docs)
+// StepDocuments returns the step documents of a workflow, enriched with
+// information about their job including its matrix values and runner class
+// and fields of their run.
+func StepDocuments(store *storage.Store, workflowID int64, opts *DocumentOptions) iter.Seq[Document] {
+	return func(yield func(Document) bool) {
+		versions := newWorkflowVersions(store, workflowID, opts.workflow())
+		runners := loadRunners(store)
 		for data, err := range store.IterJobs(workflowID) {
 			if err != nil {
 				slog.Warn("error reading jobs", "error", err)
@@ -133,6 +360,8 @@ func IndexSteps(ctx context.Context, client *Client, store *storage.Store, workf
 				continue
 			}
 
+			run := runContext(store, workflowID, jobsResp.Jobs, opts.runFields())
+			wf := versions.workflow(headSHA(jobsResp.Jobs))
 			for _, job := range jobsResp.Jobs {
 				jobID, _ := job["id"].(float64)
 				jobName, _ := job["name"].(string)
@@ -145,6 +374,7 @@ func IndexSteps(ctx context.Context, client *Client, store *storage.Store, workf
 
 				// Convert API URL to HTML URL: api.github.com/repos/... -> github.com/...
 				runHTMLURL := strings.Replace(runURL, "api.github.com/repos", "github.com", 1)
+				runner := classifyRunner(job, runners)
 
 				stepsRaw, ok := job["steps"].([]any)
 				if !ok {
@@ -169,35 +399,251 @@ func IndexSteps(ctx context.Context, client *Client, store *storage.Store, workf
 					step["run_html_url"] = runHTMLURL
 					step["run_attempt"] = runAttempt
 					step["head_sha"] = headSHA
+					addMatrixFields(step, "job_", jobName, wf)
+					runner.addTo(step)
+					maps.Copy(step, run)
 
-					docs <- Document{
+					if !yield(Document{
 						ID:   strconv.FormatInt(int64(jobID), 10) + "-" + strconv.FormatInt(int64(stepNumber), 10),
 						Body: step,
+					}) {
+						return
 					}
 				}
 			}
 		}
-	}()
+	}
+}
+
+// IndexOptions configures the IndexRuns, IndexJobs, IndexSteps and IndexAll operations.
+type IndexOptions struct {
+	Naming IndexNaming
+	// Full indexes all documents. By default only documents of runs whose
+	// stored files changed since they were last indexed are sent.
+	Full bool
+	// DataStreams writes documents into data streams. Documents get an
+	// @timestamp field and are only created, never updated. Documents without
+	// a time are skipped.
+	DataStreams bool
+	// Documents configures how documents are built.
+	Documents DocumentOptions
+	// DryRun writes the bulk action and document lines to the writer instead
+	// of sending them. The client may be nil in which case all documents are
+	// written. The index state is left untouched.
+	DryRun io.Writer
+
+	// rebuild redirects documents into new indices if set.
+	rebuild *rebuildTargets
+}
+
+// IndexRuns indexes workflow runs into Elasticsearch.
+func IndexRuns(ctx context.Context, client *Client, store *storage.Store, workflowID int64, opts *IndexOptions) (*BulkResult, error) {
+	return index(ctx, client, store, KindRuns, RunDocuments(store, workflowID, opts.documentOptions()), workflowID, opts)
+}
+
+// IndexJobs indexes workflow jobs into Elasticsearch.
+func IndexJobs(ctx context.Context, client *Client, store *storage.Store, workflowID int64, opts *IndexOptions) (*BulkResult, error) {
+	return index(ctx, client, store, KindJobs, JobDocuments(store, workflowID, opts.documentOptions()), workflowID, opts)
+}
+
+// IndexSteps indexes workflow steps into Elasticsearch.
+func IndexSteps(ctx context.Context, client *Client, store *storage.Store, workflowID int64, opts *IndexOptions) (*BulkResult, error) {
+	return index(ctx, client, store, KindSteps, StepDocuments(store, workflowID, opts.documentOptions()), workflowID, opts)
+}
+
+// documentOptions returns the options for building documents.
+func (o *IndexOptions) documentOptions() *DocumentOptions {
+	if o == nil {
+		return nil
+	}
+	return &o.Documents
+}
+
+// index indexes documents of the given kind into the indices named by the options.
+func index(ctx context.Context, client *Client, store *storage.Store, kind string, docs iter.Seq[Document], workflowID int64, opts *IndexOptions) (*BulkResult, error) {
+	if opts == nil {
+		opts = &IndexOptions{}
+	}
 
-	result, err := client.BulkIndex(ctx, "steps", docs)
+	tracker, err := newIndexTracker(client, store, kind, workflowID, opts)
 	if err != nil {
-		return result, fmt.Errorf("indexing steps: %w", err)
+		return nil, err
 	}
 
-	slog.Info("indexed steps", "total", result.Total, "successful", result.Successful, "failed", result.Failed)
+	named := func(yield func(Document) bool) {
+		for doc := range docs {
+			body, _ := doc.Body.(map[string]any)
+			if !tracker.include(doc.ID, body) {
+				continue
+			}
+			t := documentTime(kind, body)
+			if opts.DataStreams {
+				if t.IsZero() {
+					slog.Debug("skipping document without time", "kind", kind, "id", doc.ID)
+					continue
+				}
+				body["@timestamp"] = t.UTC().Format(time.RFC3339)
+				doc.Create = true
+			}
+			doc.Index = opts.Naming.Name(kind, workflowID, t)
+			if opts.rebuild != nil {
+				doc.Index = opts.rebuild.target(doc.Index)
+			}
+			if !yield(doc) {
+				return
+			}
+		}
+	}
+
+	// stop reading documents should indexing fail
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var result *BulkResult
+	if opts.DryRun != nil {
+		result, err = writeBulk(opts.DryRun, kind, sendDocuments(ctx, named))
+	} else {
+		result, err = client.BulkIndex(ctx, kind, sendDocuments(ctx, named))
+	}
+	if err != nil {
+		return result, fmt.Errorf("indexing %s: %w", kind, err)
+	}
+	if opts.rebuild != nil && result.Failed > 0 {
+		return result, fmt.Errorf("indexing %s: %d documents failed to index", kind, result.Failed)
+	}
+	tracker.fail(result.FailedIDs)
+	if err := tracker.save(); err != nil {
+		return result, err
+	}
+
+	slog.Info("indexed "+kind, "total", result.Total, "successful", result.Successful, "failed", result.Failed, "unchanged", tracker.unchanged)
 	return result, nil
 }
 
+// IndexDocuments indexes documents of the given kind that are not built from
+// stored runs, such as analysis results, into the index named for the
+// workflow. Incremental indexing does not apply so all documents are sent.
+func IndexDocuments(ctx context.Context, client *Client, kind string, workflowID int64, docs iter.Seq[Document], naming IndexNaming) (*BulkResult, error) {
+	named := func(yield func(Document) bool) {
+		for doc := range docs {
+			body, _ := doc.Body.(map[string]any)
+			doc.Index = naming.Name(kind, workflowID, documentTime(kind, body))
+			if !yield(doc) {
+				return
+			}
+		}
+	}
+
+	// stop reading documents should indexing fail
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result, err := client.BulkIndex(ctx, kind, sendDocuments(ctx, named))
+	if err != nil {
+		return result, fmt.Errorf("indexing %s: %w", kind, err)
+	}
+	slog.Info("indexed "+kind, "total", result.Total, "successful", result.Successful, "failed", result.Failed)
+	return result, nil
+}
+
+// sendDocuments sends documents on the returned channel until they are
+// exhausted or ctx is done.
+func sendDocuments(ctx context.Context, docs iter.Seq[Document]) <-chan Document {
+	ch := make(chan Document)
+
+	go func() {
+		defer close(ch)
+		for doc := range docs {
+			select {
+			case ch <- doc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
 // IndexAll indexes runs, jobs, and steps into Elasticsearch.
-func IndexAll(ctx context.Context, client *Client, store *storage.Store, workflowID int64) error {
-	if _, err := IndexRuns(ctx, client, store, workflowID); err != nil {
+func IndexAll(ctx context.Context, client *Client, store *storage.Store, workflowID int64, opts *IndexOptions) error {
+	if _, err := IndexRuns(ctx, client, store, workflowID, opts); err != nil {
 		return err
 	}
-	if _, err := IndexJobs(ctx, client, store, workflowID); err != nil {
+	if _, err := IndexJobs(ctx, client, store, workflowID, opts); err != nil {
 		return err
 	}
-	if _, err := IndexSteps(ctx, client, store, workflowID); err != nil {
+	if _, err := IndexSteps(ctx, client, store, workflowID, opts); err != nil {
 		return err
 	}
 	return nil
 }
+
+// rebuildTargets maps aliases to the new indices documents are written to
+// during a rebuild.
+type rebuildTargets struct {
+	suffix string
+
+	mu      sync.Mutex
+	indices map[string]string
+}
+
+// target returns the new index for the alias.
+func (r *rebuildTargets) target(alias string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index, ok := r.indices[alias]
+	if !ok {
+		index = alias + r.suffix
+		r.indices[alias] = index
+	}
+	return index
+}
+
+// all returns the new indices.
+func (r *rebuildTargets) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Collect(maps.Values(r.indices))
+}
+
+// Rebuild indexes runs, jobs, and steps of the given workflows into new
+// timestamped indices. Once all are indexed, aliases named like the indices
+// are atomically swapped to the new indices in a single request and the old
+// indices are deleted. The new indices are deleted if any document fails to
+// index or the swap fails, leaving the aliases untouched.
+//
+// Documents of workflows not passed are not carried over, so pass all
+// workflows whose documents share the indices.
+func Rebuild(ctx context.Context, client *Client, store *storage.Store, workflowIDs []int64, opts *IndexOptions) error {
+	if opts == nil {
+		opts = &IndexOptions{}
+	}
+	targets := &rebuildTargets{
+		suffix:  "-" + time.Now().UTC().Format("20060102150405"),
+		indices: make(map[string]string),
+	}
+	rebuildOpts := *opts
+	rebuildOpts.rebuild = targets
+
+	for _, workflowID := range workflowIDs {
+		if err := IndexAll(ctx, client, store, workflowID, &rebuildOpts); err != nil {
+			if cleanupErr := client.DeleteIndices(context.WithoutCancel(ctx), targets.all()...); cleanupErr != nil {
+				slog.Warn("error deleting new indices", "error", cleanupErr)
+			}
+			return fmt.Errorf("rebuilding workflow %d: %w", workflowID, err)
+		}
+	}
+
+	old, err := client.SwapAliases(ctx, targets.indices)
+	if err != nil {
+		if cleanupErr := client.DeleteIndices(context.WithoutCancel(ctx), targets.all()...); cleanupErr != nil {
+			slog.Warn("error deleting new indices", "error", cleanupErr)
+		}
+		return err
+	}
+
+	return client.DeleteIndices(ctx, old...)
+}
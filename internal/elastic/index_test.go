@@ -0,0 +1,156 @@
+package elastic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/teleivo/github-action-metrics/internal/storage"
+	"github.com/teleivo/github-action-metrics/internal/workflow"
+)
+
+func TestStepDocumentsRunFields(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1,"event":"pull_request","actor":{"login":"teleivo"},"pull_requests":[{"number":12},{"number":13}]}`)
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[{"id":7,"run_id":1,"steps":[{"number":1}]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var docs []Document
+	for doc := range StepDocuments(store, 10954, &DocumentOptions{RunFields: []string{"event", "actor.login", "pull_requests.number", "head_branch"}}) {
+		docs = append(docs, doc)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("StepDocuments() returned %d documents, want 1", len(docs))
+	}
+
+	step := docs[0].Body.(map[string]any)
+	want := map[string]any{
+		"run_event":                "pull_request",
+		"run_actor_login":          "teleivo",
+		"run_pull_requests_number": []any{float64(12), float64(13)},
+	}
+	for field, value := range want {
+		if !reflect.DeepEqual(step[field], value) {
+			t.Errorf("StepDocuments() %s = %v, want %v", field, step[field], value)
+		}
+	}
+	if _, ok := step["run_head_branch"]; ok {
+		t.Error("StepDocuments() added missing run field run_head_branch")
+	}
+}
+
+func TestRunDocumentsWorkflowFile(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	mustSaveRun(t, store, 1, `{"id":1,"head_sha":"`+sha+`"}`)
+	mustSaveRun(t, store, 2, `{"id":2,"head_sha":"a1b2c3"}`)
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[
+		{"id":7,"run_id":1,"name":"build","started_at":"2021-10-20T10:00:00Z","completed_at":"2021-10-20T10:05:00Z"},
+		{"id":8,"run_id":1,"name":"test","started_at":"2021-10-20T10:05:00Z","completed_at":"2021-10-20T10:15:00Z"}
+	]}`)); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := store.SaveWorkflowFile(10954, sha, []byte("jobs:\n  build: {}\n  test:\n    needs: build\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(map[string]map[string]any)
+	for doc := range RunDocuments(store, 10954, nil) {
+		docs[doc.ID] = doc.Body.(map[string]any)
+	}
+
+	if got := docs["1"]["workflow_file_hash"]; got != hash {
+		t.Errorf("RunDocuments() workflow_file_hash = %v, want %s", got, hash)
+	}
+	if got, want := docs["1"]["critical_path"], []string{"build", "test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RunDocuments() critical_path = %v, want %v", got, want)
+	}
+	if _, ok := docs["2"]["workflow_file_hash"]; ok {
+		t.Error("RunDocuments() added workflow_file_hash to run without stored workflow file")
+	}
+}
+
+func TestJobDocumentsMatrix(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSaveRun(t, store, 1, `{"id":1}`)
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[
+		{"id":7,"run_id":1,"name":"test (ubuntu-latest, 17)","steps":[{"number":1}]},
+		{"id":8,"run_id":1,"name":"build"},
+		{"id":9,"run_id":1,"name":"deploy (production)"}
+	]}`)); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := workflow.Parse([]byte("jobs:\n  test:\n    strategy:\n      matrix:\n        os: [ubuntu-latest]\n        java: [17]\n  build: {}\n  deploy:\n    name: deploy (production)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts *DocumentOptions
+		want map[string]any
+	}{
+		{
+			name: "without workflow",
+			want: map[string]any{
+				"base_name":     "test",
+				"matrix_values": []string{"ubuntu-latest", "17"},
+			},
+		},
+		{
+			name: "with workflow",
+			opts: &DocumentOptions{Workflow: wf},
+			want: map[string]any{
+				"base_name":     "test",
+				"matrix_values": []string{"ubuntu-latest", "17"},
+				"matrix":        map[string]string{"os": "ubuntu-latest", "java": "17"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := make(map[string]map[string]any)
+			for doc := range JobDocuments(store, 10954, tt.opts) {
+				docs[doc.ID] = doc.Body.(map[string]any)
+			}
+
+			for field, value := range tt.want {
+				if !reflect.DeepEqual(docs["7"][field], value) {
+					t.Errorf("JobDocuments() %s = %v, want %v", field, docs["7"][field], value)
+				}
+			}
+			if _, ok := docs["7"]["matrix"]; ok && tt.opts == nil {
+				t.Error("JobDocuments() added matrix without workflow")
+			}
+			if docs["8"]["base_name"] != "build" {
+				t.Errorf("JobDocuments() base_name = %v, want build", docs["8"]["base_name"])
+			}
+			if _, ok := docs["8"]["matrix_values"]; ok {
+				t.Error("JobDocuments() added matrix_values to job without matrix")
+			}
+			if _, ok := docs["9"]["matrix_values"]; ok == (tt.opts != nil) {
+				t.Errorf("JobDocuments() matrix_values of job named with parentheses = %v", docs["9"]["matrix_values"])
+			}
+
+			for doc := range StepDocuments(store, 10954, tt.opts) {
+				step := doc.Body.(map[string]any)
+				for field, value := range tt.want {
+					if !reflect.DeepEqual(step["job_"+field], value) {
+						t.Errorf("StepDocuments() job_%s = %v, want %v", field, step["job_"+field], value)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// durationScripts compute the duration runtime field of each kind of document.
+var durationScripts = map[string]string{
+	KindRuns:  "if (doc['jobs_completed_at'].size() == 0) { emit(0) } else { emit(doc['jobs_completed_at'].value.toInstant().toEpochMilli() - doc['jobs_started_at'].value.toInstant().toEpochMilli()) }",
+	KindJobs:  "emit(doc['completed_at'].value.toInstant().toEpochMilli() - doc['started_at'].value.toInstant().toEpochMilli())",
+	KindSteps: "emit(doc['completed_at'].value.toInstant().toEpochMilli() - doc['started_at'].value.toInstant().toEpochMilli())",
+}
+
+// CreateIndexPatterns creates Kibana index patterns for the runs, jobs and
+// steps indices named by naming. Each index pattern has a duration runtime
+// field formatted in minutes. Existing index patterns are overridden.
+//
+// The client must be configured with the Kibana URL.
+func CreateIndexPatterns(ctx context.Context, kibana *Client, naming IndexNaming) error {
+	for _, kind := range []string{KindRuns, KindJobs, KindSteps} {
+		pattern := naming.Pattern(kind)
+		// runs also have a created_at field in case you want it to be the time field
+		timeField := timeFields[kind][0]
+
+		body := map[string]any{
+			"override":       true,
+			"refresh_fields": true,
+			"index_pattern": map[string]any{
+				"id":            pattern,
+				"title":         pattern,
+				"timeFieldName": timeField,
+				"runtimeFieldMap": map[string]any{
+					"duration": map[string]any{
+						"type":   "long",
+						"script": map[string]any{"source": durationScripts[kind]},
+					},
+				},
+				"fieldFormats": map[string]any{
+					"duration": map[string]any{
+						"id": "duration",
+						"params": map[string]any{
+							"inputFormat":    "milliseconds",
+							"outputFormat":   "asMinutes",
+							"useShortSuffix": true,
+							"showSuffix":     true,
+						},
+					},
+				},
+			},
+		}
+		if err := kibana.createIndexPattern(ctx, body); err != nil {
+			return fmt.Errorf("creating index pattern %q: %w", pattern, err)
+		}
+		slog.Info("created index pattern", "kind", kind, "pattern", pattern)
+	}
+	return nil
+}
+
+func (c *Client) createIndexPattern(ctx context.Context, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding index pattern: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/index_patterns/index_pattern", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("kbn-xsrf", "true")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultPolicyName is the name of the lifecycle policy installed by Setup.
+const DefaultPolicyName = "gham"
+
+// templatePriority is above the priority of the built-in templates so that
+// they do not take precedence for matching names.
+const templatePriority = 200
+
+var (
+	lifecycleAgePattern  = regexp.MustCompile(`^\d+(d|h|m|s)$`)
+	lifecycleSizePattern = regexp.MustCompile(`^\d+(b|kb|mb|gb|tb)$`)
+)
+
+// LifecyclePolicy configures the phases of the lifecycle policy managing the
+// data streams. Ages are given like 30d or 12h and sizes like 50gb.
+type LifecyclePolicy struct {
+	Name string
+	// RolloverMaxAge and RolloverMaxSize roll over to a new backing index in
+	// the hot phase once either is reached.
+	RolloverMaxAge  string
+	RolloverMaxSize string
+	// WarmAfter moves backing indices to the warm phase where they are made
+	// read-only and force merged. An empty value skips the warm phase.
+	WarmAfter string
+	// DeleteAfter deletes backing indices. An empty value keeps them forever.
+	DeleteAfter string
+}
+
+// Validate returns an error if a phase setting is not in a valid format.
+func (p LifecyclePolicy) Validate() error {
+	if p.Name == "" {
+		return errors.New("policy name is required")
+	}
+	if p.RolloverMaxAge == "" && p.RolloverMaxSize == "" {
+		return errors.New("rollover max age or max size is required")
+	}
+	for _, age := range []string{p.RolloverMaxAge, p.WarmAfter, p.DeleteAfter} {
+		if age != "" && !lifecycleAgePattern.MatchString(age) {
+			return fmt.Errorf("invalid age %q, use a number followed by d, h, m or s like 30d", age)
+		}
+	}
+	if p.RolloverMaxSize != "" && !lifecycleSizePattern.MatchString(p.RolloverMaxSize) {
+		return fmt.Errorf("invalid size %q, use a number followed by b, kb, mb, gb or tb like 50gb", p.RolloverMaxSize)
+	}
+	return nil
+}
+
+// Setup installs the lifecycle policy and index templates turning the runs,
+// jobs and steps indices named by naming into data streams. Elasticsearch
+// uses an ILM policy and OpenSearch an ISM policy. The client must be
+// connected. Existing policies and templates of the same name are replaced.
+func Setup(ctx context.Context, client *Client, naming IndexNaming, policy LifecyclePolicy) error {
+	info := client.Info()
+	if info == nil {
+		return errors.New("client is not connected")
+	}
+	if err := checkDataStreamsSupported(info); err != nil {
+		return err
+	}
+
+	var patterns []string
+	for _, kind := range []string{KindRuns, KindJobs, KindSteps} {
+		patterns = append(patterns, naming.Pattern(kind))
+	}
+
+	if info.Distribution == DistributionOpenSearch {
+		if err := client.putISMPolicy(ctx, policy, patterns); err != nil {
+			return err
+		}
+	} else {
+		if err := client.put(ctx, "/_ilm/policy/"+url.PathEscape(policy.Name), ilmPolicy(policy, info.Major >= 8)); err != nil {
+			return fmt.Errorf("installing ILM policy %q: %w", policy.Name, err)
+		}
+	}
+	slog.Info("installed lifecycle policy", "policy", policy.Name, "distribution", info.Distribution)
+
+	for i, kind := range []string{KindRuns, KindJobs, KindSteps} {
+		name := policy.Name + "-" + kind
+		settings := map[string]any{}
+		if info.Distribution == DistributionElasticsearch {
+			settings["index.lifecycle.name"] = policy.Name
+		}
+		template := map[string]any{
+			"index_patterns": []string{patterns[i]},
+			"data_stream":    map[string]any{},
+			"priority":       templatePriority,
+			"template": map[string]any{
+				"settings": settings,
+				"mappings": map[string]any{
+					"properties": map[string]any{
+						"@timestamp": map[string]any{"type": "date"},
+					},
+				},
+			},
+			"_meta": map[string]any{"managed_by": "gham"},
+		}
+		if err := client.put(ctx, "/_index_template/"+url.PathEscape(name), template); err != nil {
+			return fmt.Errorf("installing index template %q: %w", name, err)
+		}
+		slog.Info("installed index template", "template", name, "pattern", patterns[i])
+	}
+	return nil
+}
+
+// checkDataStreamsSupported returns an error if the cluster does not support
+// data streams which were added in Elasticsearch 7.9.
+func checkDataStreamsSupported(info *ClusterInfo) error {
+	if info.Distribution != DistributionElasticsearch || info.Major > 7 {
+		return nil
+	}
+	parts := strings.Split(info.Version, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err == nil && minor < 9 {
+		return fmt.Errorf("data streams require Elasticsearch 7.9 or newer, got %s", info.Version)
+	}
+	return nil
+}
+
+// ilmPolicy returns the Elasticsearch ILM policy. The rollover size applies
+// to the primary shard size if primaryShardSize is set, which replaced the
+// deprecated index size in Elasticsearch 8.
+func ilmPolicy(policy LifecyclePolicy, primaryShardSize bool) map[string]any {
+	rollover := map[string]any{}
+	if policy.RolloverMaxAge != "" {
+		rollover["max_age"] = policy.RolloverMaxAge
+	}
+	if policy.RolloverMaxSize != "" && primaryShardSize {
+		rollover["max_primary_shard_size"] = policy.RolloverMaxSize
+	} else if policy.RolloverMaxSize != "" {
+		rollover["max_size"] = policy.RolloverMaxSize
+	}
+	phases := map[string]any{
+		"hot": map[string]any{
+			"actions": map[string]any{"rollover": rollover},
+		},
+	}
+	if policy.WarmAfter != "" {
+		phases["warm"] = map[string]any{
+			"min_age": policy.WarmAfter,
+			"actions": map[string]any{
+				"readonly":   map[string]any{},
+				"forcemerge": map[string]any{"max_num_segments": 1},
+			},
+		}
+	}
+	if policy.DeleteAfter != "" {
+		phases["delete"] = map[string]any{
+			"min_age": policy.DeleteAfter,
+			"actions": map[string]any{"delete": map[string]any{}},
+		}
+	}
+	return map[string]any{"policy": map[string]any{"phases": phases}}
+}
+
+// ismPolicy returns the OpenSearch ISM policy applied to new backing indices
+// matching patterns. ISM measures ages from index creation.
+func ismPolicy(policy LifecyclePolicy, patterns []string) map[string]any {
+	rollover := map[string]any{}
+	if policy.RolloverMaxAge != "" {
+		rollover["min_index_age"] = policy.RolloverMaxAge
+	}
+	if policy.RolloverMaxSize != "" {
+		rollover["min_primary_shard_size"] = policy.RolloverMaxSize
+	}
+
+	hot := map[string]any{
+		"name":        "hot",
+		"actions":     []any{map[string]any{"rollover": rollover}},
+		"transitions": []any{},
+	}
+	states := []any{hot}
+	last := hot
+	transition := func(state map[string]any, age string) {
+		last["transitions"] = []any{map[string]any{
+			"state_name": state["name"],
+			"conditions": map[string]any{"min_index_age": age},
+		}}
+		states = append(states, state)
+		last = state
+	}
+	if policy.WarmAfter != "" {
+		transition(map[string]any{
+			"name": "warm",
+			"actions": []any{
+				map[string]any{"read_only": map[string]any{}},
+				map[string]any{"force_merge": map[string]any{"max_num_segments": 1}},
+			},
+			"transitions": []any{},
+		}, policy.WarmAfter)
+	}
+	if policy.DeleteAfter != "" {
+		transition(map[string]any{
+			"name":        "delete",
+			"actions":     []any{map[string]any{"delete": map[string]any{}}},
+			"transitions": []any{},
+		}, policy.DeleteAfter)
+	}
+
+	return map[string]any{
+		"policy": map[string]any{
+			"description":   "Lifecycle of GitHub Actions metrics installed by gham",
+			"default_state": "hot",
+			"states":        states,
+			"ism_template": []any{map[string]any{
+				"index_patterns": backingIndexPatterns(patterns),
+				"priority":       templatePriority,
+			}},
+		},
+	}
+}
+
+// backingIndexPatterns returns patterns matching the backing indices of the
+// data streams matching patterns.
+func backingIndexPatterns(patterns []string) []string {
+	backing := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		backing[i] = ".ds-" + pattern + "-*"
+	}
+	return backing
+}
+
+// putISMPolicy creates or replaces the ISM policy. Replacing a policy
+// requires its current sequence number and primary term.
+func (c *Client) putISMPolicy(ctx context.Context, policy LifecyclePolicy, patterns []string) error {
+	path := "/_plugins/_ism/policies/" + url.PathEscape(policy.Name)
+
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+	case resp.StatusCode >= 400:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("getting ISM policy %q failed with status %d: %s", policy.Name, resp.StatusCode, body)
+	default:
+		var existing struct {
+			SeqNo       int64 `json:"_seq_no"`
+			PrimaryTerm int64 `json:"_primary_term"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&existing); err != nil {
+			return fmt.Errorf("decoding ISM policy: %w", err)
+		}
+		path += "?if_seq_no=" + strconv.FormatInt(existing.SeqNo, 10) + "&if_primary_term=" + strconv.FormatInt(existing.PrimaryTerm, 10)
+	}
+
+	if err := c.put(ctx, path, ismPolicy(policy, patterns)); err != nil {
+		return fmt.Errorf("installing ISM policy %q: %w", policy.Name, err)
+	}
+	return nil
+}
+
+// put sends body as JSON to path.
+func (c *Client) put(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLifecyclePolicies(t *testing.T) {
+	policy := LifecyclePolicy{Name: "gham", RolloverMaxAge: "30d", RolloverMaxSize: "50gb", WarmAfter: "7d", DeleteAfter: "365d"}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	got, err := json.Marshal(ilmPolicy(policy, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"policy":{"phases":{"delete":{"actions":{"delete":{}},"min_age":"365d"},"hot":{"actions":{"rollover":{"max_age":"30d","max_primary_shard_size":"50gb"}}},"warm":{"actions":{"forcemerge":{"max_num_segments":1},"readonly":{}},"min_age":"7d"}}}}`
+	if string(got) != want {
+		t.Errorf("ilmPolicy() = %s, want %s", got, want)
+	}
+
+	var ism struct {
+		Policy struct {
+			States []struct {
+				Name        string `json:"name"`
+				Transitions []struct {
+					StateName  string `json:"state_name"`
+					Conditions struct {
+						MinIndexAge string `json:"min_index_age"`
+					} `json:"conditions"`
+				} `json:"transitions"`
+			} `json:"states"`
+		} `json:"policy"`
+	}
+	data, err := json.Marshal(ismPolicy(policy, []string{"runs"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &ism); err != nil {
+		t.Fatal(err)
+	}
+	states := ism.Policy.States
+	if len(states) != 3 || states[0].Name != "hot" || states[1].Name != "warm" || states[2].Name != "delete" {
+		t.Fatalf("ismPolicy() states = %+v, want hot, warm and delete", states)
+	}
+	if tr := states[0].Transitions; len(tr) != 1 || tr[0].StateName != "warm" || tr[0].Conditions.MinIndexAge != "7d" {
+		t.Errorf("ismPolicy() hot transitions = %+v", tr)
+	}
+	if tr := states[1].Transitions; len(tr) != 1 || tr[0].StateName != "delete" || tr[0].Conditions.MinIndexAge != "365d" {
+		t.Errorf("ismPolicy() warm transitions = %+v", tr)
+	}
+	if len(states[2].Transitions) != 0 {
+		t.Errorf("ismPolicy() delete transitions = %+v, want none", states[2].Transitions)
+	}
+}
+
+func TestLifecyclePolicyValidate(t *testing.T) {
+	tests := []LifecyclePolicy{
+		{Name: "", RolloverMaxAge: "30d"},
+		{Name: "gham"},
+		{Name: "gham", RolloverMaxAge: "30 days"},
+		{Name: "gham", RolloverMaxAge: "30d", RolloverMaxSize: "50"},
+	}
+	for _, policy := range tests {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", policy)
+		}
+	}
+}
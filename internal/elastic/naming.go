@@ -0,0 +1,132 @@
+package elastic
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of documents gham indexes. The kind is also the default index name.
+const (
+	KindRuns  = "runs"
+	KindJobs  = "jobs"
+	KindSteps = "steps"
+	// KindActions are the actions referenced by workflow files.
+	KindActions = "actions"
+)
+
+// DefaultIndexTemplate is the index name template used if none is configured.
+const DefaultIndexTemplate = "{kind}"
+
+var placeholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// IndexNaming configures the names of the runs, jobs and steps indices.
+//
+// The template supports the placeholders {kind} (runs, jobs, steps or actions),
+// {owner}, {repo}, {workflow_id}, and {yyyy} and {MM} for the year and month
+// of the document. The prefix is prepended to the expanded template.
+type IndexNaming struct {
+	Prefix   string
+	Template string
+	Owner    string
+	Repo     string
+}
+
+// Validate returns an error if the template contains unknown placeholders or
+// placeholders whose values are not configured.
+func (n IndexNaming) Validate() error {
+	template := n.template()
+	if !strings.Contains(template, "{kind}") {
+		return errors.New("index name template must contain {kind}")
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		switch placeholder {
+		case "{kind}", "{workflow_id}", "{yyyy}", "{MM}":
+		case "{owner}":
+			if n.Owner == "" {
+				return errors.New("index name template uses {owner} but no owner is configured")
+			}
+		case "{repo}":
+			if n.Repo == "" {
+				return errors.New("index name template uses {repo} but no repo is configured")
+			}
+		default:
+			return fmt.Errorf("unknown placeholder %s in index name template", placeholder)
+		}
+	}
+	return nil
+}
+
+// Name returns the index for a document of the given kind and workflow
+// with time t. A zero t expands the time placeholders to 0000 and 00.
+func (n IndexNaming) Name(kind string, workflowID int64, t time.Time) string {
+	yyyy, mm := "0000", "00"
+	if !t.IsZero() {
+		t = t.UTC()
+		yyyy, mm = t.Format("2006"), t.Format("01")
+	}
+	return n.expand(kind, workflowID, yyyy, mm)
+}
+
+// Pattern returns an index pattern matching the indices of the given kind
+// across all workflows and times.
+func (n IndexNaming) Pattern(kind string) string {
+	pattern := n.expand(kind, -1, "*", "*")
+	// collapse wildcards of adjacent placeholders
+	for strings.Contains(pattern, "**") {
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+	}
+	return pattern
+}
+
+// expand replaces the placeholders of the template. A negative workflowID
+// expands to a wildcard.
+func (n IndexNaming) expand(kind string, workflowID int64, yyyy, mm string) string {
+	workflow := "*"
+	if workflowID >= 0 {
+		workflow = strconv.FormatInt(workflowID, 10)
+	}
+	r := strings.NewReplacer(
+		"{kind}", kind,
+		"{owner}", strings.ToLower(n.Owner),
+		"{repo}", strings.ToLower(n.Repo),
+		"{workflow_id}", workflow,
+		"{yyyy}", yyyy,
+		"{MM}", mm,
+	)
+	return n.Prefix + r.Replace(n.template())
+}
+
+func (n IndexNaming) template() string {
+	if n.Template == "" {
+		return DefaultIndexTemplate
+	}
+	return n.Template
+}
+
+// timeFields lists per kind the fields holding the document time in order of preference.
+var timeFields = map[string][]string{
+	KindRuns:    {"run_started_at", "created_at"},
+	KindJobs:    {"started_at", "created_at"},
+	KindSteps:   {"started_at"},
+	KindActions: {"last_run_at"},
+}
+
+// documentTime returns the time of a document of the given kind or the zero
+// time if it has none.
+func documentTime(kind string, doc map[string]any) time.Time {
+	for _, field := range timeFields[kind] {
+		value, ok := doc[field].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
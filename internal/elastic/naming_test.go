@@ -0,0 +1,70 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndexNaming(t *testing.T) {
+	created := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		naming      IndexNaming
+		wantName    string
+		wantPattern string
+		wantErr     bool
+	}{
+		{
+			name:        "default",
+			naming:      IndexNaming{},
+			wantName:    "runs",
+			wantPattern: "runs",
+		},
+		{
+			name:        "prefix",
+			naming:      IndexNaming{Prefix: "gham-"},
+			wantName:    "gham-runs",
+			wantPattern: "gham-runs",
+		},
+		{
+			name:        "template",
+			naming:      IndexNaming{Template: "{owner}-{repo}-{kind}-{workflow_id}-{yyyy}.{MM}", Owner: "DHIS2", Repo: "dhis2-core"},
+			wantName:    "dhis2-dhis2-core-runs-10954-2021.10",
+			wantPattern: "dhis2-dhis2-core-runs-*-*.*",
+		},
+		{
+			name:    "missing kind",
+			naming:  IndexNaming{Template: "{repo}", Repo: "dhis2-core"},
+			wantErr: true,
+		},
+		{
+			name:    "missing owner",
+			naming:  IndexNaming{Template: "{owner}-{kind}"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown placeholder",
+			naming:  IndexNaming{Template: "{kind}-{day}"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.naming.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.naming.Name(KindRuns, 10954, created); got != tt.wantName {
+				t.Errorf("Name() = %q, want %q", got, tt.wantName)
+			}
+			if got := tt.naming.Pattern(KindRuns); got != tt.wantPattern {
+				t.Errorf("Pattern() = %q, want %q", got, tt.wantPattern)
+			}
+		})
+	}
+}
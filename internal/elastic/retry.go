@@ -0,0 +1,114 @@
+package elastic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults for retrying requests.
+const (
+	DefaultMaxRetries   = 5
+	DefaultMaxRetryTime = 2 * time.Minute
+)
+
+// Backoff settings for retrying requests.
+const (
+	retryDelay    = 500 * time.Millisecond
+	retryMaxDelay = 30 * time.Second
+)
+
+// retryable reports whether a response with the given status is retried.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends the request, retrying connection errors and responses with status
+// 429, 502, 503 and 504 with exponential backoff and jitter. Retries stop
+// after the maximum number of retries or retry time of the client, or once
+// the context of the request is done. The last response or error is returned.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errors.New("cannot retry request without GetBody")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := c.client.Do(attemptReq)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if err != nil && !retryableError(ctx, err) {
+			return nil, err
+		}
+
+		delay := retryDelayFor(attempt, resp)
+		if attempt >= c.maxRetries || time.Since(start)+delay > c.maxRetryTime {
+			return resp, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		if err != nil {
+			slog.Warn("retrying request", "method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "delay", delay, "error", err)
+		} else {
+			slog.Warn("retrying request", "method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "delay", delay, "status", resp.StatusCode)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryableError reports whether a request that failed with err is retried.
+// Errors due to the context or certificate verification are not retried.
+func retryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	return !errors.As(err, &certErr)
+}
+
+// retryDelayFor returns the delay before the retry following attempt. The
+// backoff is extended to the Retry-After seconds of the response if given.
+func retryDelayFor(attempt int, resp *http.Response) time.Duration {
+	delay := backoff(attempt)
+	if resp != nil {
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			delay = max(delay, time.Duration(retryAfter)*time.Second)
+		}
+	}
+	return delay
+}
+
+// backoff returns the delay before the retry following attempt using
+// exponential backoff with full jitter.
+func backoff(attempt int) time.Duration {
+	limit := min(retryDelay<<min(attempt, 16), retryMaxDelay)
+	return rand.N(limit)
+}
@@ -0,0 +1,83 @@
+package elastic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantStatus int
+		wantCalls  int
+	}{
+		{
+			name:       "retries unavailable",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+		},
+		{
+			name:       "gives up after max retries",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			maxRetries: 1,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCalls:  2,
+		},
+		{
+			name:       "does not retry client errors",
+			statuses:   []int{http.StatusBadRequest, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(tt.statuses[calls])
+				calls++
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{URL: server.URL, MaxRetries: tt.maxRetries, MaxRetryTime: time.Minute})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := client.newRequest(context.Background(), http.MethodPost, "/_bulk", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.do(req)
+			if err != nil {
+				t.Fatalf("do() error = %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("do() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("do() sent %d requests, want %d", calls, tt.wantCalls)
+			}
+			for _, body := range bodies {
+				if body != "payload" {
+					t.Errorf("do() sent body %q, want %q", body, "payload")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package elastic
+
+import (
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/billing"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// Types of runners jobs run on.
+const (
+	RunnerTypeHosted     = "github-hosted"
+	RunnerTypeSelfHosted = "self-hosted"
+)
+
+// hostedRunnerGroup is the runner group of jobs that ran on standard
+// GitHub-hosted runners.
+const hostedRunnerGroup = "GitHub Actions"
+
+var (
+	runnerCoresPattern = regexp.MustCompile(`(\d+)-?cores?\b`)
+	macOSPattern       = regexp.MustCompile(`^macos-(\d+)`)
+	// hostedImagePattern matches the labels of GitHub-hosted runner images
+	// like ubuntu-latest, windows-2022, macos-14-xlarge or ubuntu-24.04-arm.
+	hostedImagePattern = regexp.MustCompile(`^(ubuntu|windows|macos)-(latest|\d[\d.]*)(-[a-z0-9-]+)?$`)
+)
+
+// storedRunner holds the fields of a stored self-hosted runner used to
+// classify the runners of jobs.
+type storedRunner struct {
+	Name   string `json:"name"`
+	OS     string `json:"os"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// loadRunners returns the self-hosted runners stored using 'gham fetch
+// runners' by name.
+func loadRunners(store *storage.Store) map[string]storedRunner {
+	runners := make(map[string]storedRunner)
+	for data, err := range store.IterRunners() {
+		if err != nil {
+			slog.Warn("error reading runners", "error", err)
+			continue
+		}
+		var snapshot struct {
+			Runners []storedRunner `json:"runners"`
+		}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			slog.Warn("error unmarshaling runners", "error", err)
+			continue
+		}
+		for _, runner := range snapshot.Runners {
+			runners[runner.Name] = runner
+		}
+	}
+	return runners
+}
+
+// runnerClass is the normalized class of the runner a job ran on. Unknown
+// attributes are empty.
+type runnerClass struct {
+	OS   string
+	Arch string
+	Type string
+	Size string
+}
+
+// classifyRunner classifies the runner of a job from the labels the job
+// requested and, if the runner is a stored self-hosted runner, its OS and
+// labels. Runners are only typed as GitHub-hosted if the job ran in the
+// GitHub Actions runner group or requested a GitHub-hosted image. Other
+// runners, like those of an actions runner controller scale set, are left
+// untyped.
+func classifyRunner(job map[string]any, runners map[string]storedRunner) runnerClass {
+	var labels []string
+	if values, ok := job["labels"].([]any); ok {
+		for _, value := range values {
+			if label, ok := value.(string); ok {
+				labels = append(labels, strings.ToLower(label))
+			}
+		}
+	}
+	var class runnerClass
+	runnerName, _ := job["runner_name"].(string)
+	runner, stored := runners[runnerName]
+	if stored {
+		class.OS = normalizeOS(runner.OS)
+		for _, label := range runner.Labels {
+			labels = append(labels, strings.ToLower(label.Name))
+		}
+	}
+
+	switch {
+	case stored || slices.Contains(labels, "self-hosted"):
+		class.Type = RunnerTypeSelfHosted
+	case job["runner_group_name"] == hostedRunnerGroup || slices.ContainsFunc(labels, hostedImagePattern.MatchString):
+		class.Type = RunnerTypeHosted
+	}
+
+	for _, label := range labels {
+		if class.OS == "" {
+			class.OS = normalizeOS(label)
+		}
+		switch label {
+		case "x64", "arm64", "arm":
+			class.Arch = label
+		}
+		if m := runnerCoresPattern.FindStringSubmatch(label); m != nil {
+			class.Size = m[1] + "-core"
+		} else if class.Size == "" && strings.HasSuffix(label, "-xlarge") {
+			class.Size = "xlarge"
+		} else if class.Size == "" && strings.HasSuffix(label, "-large") {
+			class.Size = "large"
+		}
+	}
+
+	if class.Type == RunnerTypeHosted {
+		if class.Arch == "" {
+			class.Arch = hostedArch(labels)
+		}
+		if class.Size == "" {
+			class.Size = "standard"
+		}
+	}
+	return class
+}
+
+// normalizeOS returns linux, windows or macos for an OS name or runner label
+// or an empty string if it names none.
+func normalizeOS(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "linux" || strings.HasPrefix(name, "ubuntu"):
+		return "linux"
+	case name == "windows" || strings.HasPrefix(name, "windows-"):
+		return "windows"
+	case name == "macos" || strings.HasPrefix(name, "macos-"):
+		return "macos"
+	}
+	return ""
+}
+
+// hostedArch returns the architecture of a GitHub-hosted runner from its
+// image labels. Arm images end in -arm and macOS images run on arm64 from
+// macOS 14 on, except for the large Intel images.
+func hostedArch(labels []string) string {
+	for _, label := range labels {
+		switch {
+		case strings.HasSuffix(label, "-arm") || strings.HasSuffix(label, "-arm64"):
+			return "arm64"
+		case strings.HasSuffix(label, "-xlarge") && strings.HasPrefix(label, "macos-"):
+			return "arm64"
+		case strings.HasSuffix(label, "-large") && strings.HasPrefix(label, "macos-"):
+			return "x64"
+		case label == "macos-latest":
+			return "arm64"
+		}
+		if m := macOSPattern.FindStringSubmatch(label); m != nil {
+			if version, _ := strconv.Atoi(m[1]); version >= 14 {
+				return "arm64"
+			}
+			return "x64"
+		}
+	}
+	return "x64"
+}
+
+// addTo adds the known attributes of the runner class to doc.
+func (c runnerClass) addTo(doc map[string]any) {
+	for field, value := range map[string]string{
+		"runner_os":   c.OS,
+		"runner_arch": c.Arch,
+		"runner_type": c.Type,
+		"runner_size": c.Size,
+	} {
+		if value != "" {
+			doc[field] = value
+		}
+	}
+}
+
+// addCost adds the estimated billable_minutes and cost of a job to doc. Jobs
+// on self-hosted runners are free. Nothing is added if the runner or its
+// price is unknown or the job did not complete.
+func addCost(doc map[string]any, runner runnerClass, prices billing.PriceTable) {
+	if runner.Type == RunnerTypeSelfHosted {
+		doc["billable_minutes"] = int64(0)
+		doc["cost"] = 0.0
+		return
+	}
+	if runner.Type != RunnerTypeHosted {
+		return
+	}
+
+	started, _ := doc["started_at"].(string)
+	completed, _ := doc["completed_at"].(string)
+	start, err := time.Parse(time.RFC3339, started)
+	if err != nil {
+		return
+	}
+	end, err := time.Parse(time.RFC3339, completed)
+	if err != nil {
+		return
+	}
+	estimate, ok := prices.EstimateJob(end.Sub(start), runner.OS, runner.Arch, runner.Size)
+	if !ok {
+		return
+	}
+	doc["billable_minutes"] = estimate.BillableMinutes
+	doc["cost"] = estimate.Cost
+}
@@ -0,0 +1,89 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClassifyRunner(t *testing.T) {
+	var runners map[string]storedRunner
+	if err := json.Unmarshal([]byte(`{
+		"build-01": {"name":"build-01","os":"Linux","labels":[{"name":"self-hosted"},{"name":"Linux"},{"name":"ARM64"},{"name":"8-cores"}]}
+	}`), &runners); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		job  string
+		want runnerClass
+	}{
+		{
+			name: "hosted",
+			job:  `{"labels":["ubuntu-latest"],"runner_name":"GitHub Actions 2","runner_group_name":"GitHub Actions"}`,
+			want: runnerClass{OS: "linux", Arch: "x64", Type: RunnerTypeHosted, Size: "standard"},
+		},
+		{
+			name: "hosted larger runner",
+			job:  `{"labels":["ubuntu-22.04-16core"]}`,
+			want: runnerClass{OS: "linux", Arch: "x64", Type: RunnerTypeHosted, Size: "16-core"},
+		},
+		{
+			name: "hosted arm",
+			job:  `{"labels":["ubuntu-24.04-arm"]}`,
+			want: runnerClass{OS: "linux", Arch: "arm64", Type: RunnerTypeHosted, Size: "standard"},
+		},
+		{
+			name: "hosted macOS",
+			job:  `{"labels":["macos-13"]}`,
+			want: runnerClass{OS: "macos", Arch: "x64", Type: RunnerTypeHosted, Size: "standard"},
+		},
+		{
+			name: "hosted macOS xlarge",
+			job:  `{"labels":["macos-14-xlarge"]}`,
+			want: runnerClass{OS: "macos", Arch: "arm64", Type: RunnerTypeHosted, Size: "xlarge"},
+		},
+		{
+			name: "stored self-hosted",
+			job:  `{"labels":["self-hosted","build"],"runner_name":"build-01"}`,
+			want: runnerClass{OS: "linux", Arch: "arm64", Type: RunnerTypeSelfHosted, Size: "8-core"},
+		},
+		{
+			name: "unknown self-hosted",
+			job:  `{"labels":["self-hosted","Windows","X64"],"runner_name":"win-07"}`,
+			want: runnerClass{OS: "windows", Arch: "x64", Type: RunnerTypeSelfHosted},
+		},
+		{
+			name: "scale set",
+			job:  `{"labels":["my-arc-set"],"runner_name":"my-arc-set-abcde-runner-xyz","runner_group_name":"Default"}`,
+			want: runnerClass{},
+		},
+		{
+			name: "scale set with OS label",
+			job:  `{"labels":["linux","arm64"],"runner_name":"arc-7","runner_group_name":"Default"}`,
+			want: runnerClass{OS: "linux", Arch: "arm64"},
+		},
+		{
+			name: "hosted group without image label",
+			job:  `{"labels":["ubuntu-custom"],"runner_name":"GitHub Actions 7","runner_group_name":"GitHub Actions"}`,
+			want: runnerClass{OS: "linux", Arch: "x64", Type: RunnerTypeHosted, Size: "standard"},
+		},
+		{
+			name: "never picked up",
+			job:  `{"labels":[]}`,
+			want: runnerClass{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job map[string]any
+			if err := json.Unmarshal([]byte(tt.job), &job); err != nil {
+				t.Fatal(err)
+			}
+			if got := classifyRunner(job, runners); got != tt.want {
+				t.Errorf("classifyRunner() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
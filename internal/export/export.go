@@ -0,0 +1,264 @@
+// Package export writes the documents gham indexes in Elasticsearch to files
+// for analysis with other tools.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/elastic"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// Kind is the kind of document to export.
+type Kind string
+
+// Kinds of documents that can be exported.
+const (
+	KindRuns  Kind = "runs"
+	KindJobs  Kind = "jobs"
+	KindSteps Kind = "steps"
+)
+
+// DefaultColumns are the columns exported for each kind of document if none are selected.
+var DefaultColumns = map[Kind][]string{
+	KindRuns: {
+		"id", "workflow_id", "name", "run_number", "run_attempt", "event", "status", "conclusion",
+		"head_branch", "head_sha", "created_at", "run_started_at", "updated_at",
+		"jobs_started_at", "jobs_completed_at", "html_url",
+	},
+	KindJobs: {
+		"id", "run_id", "run_attempt", "name", "status", "conclusion", "head_sha",
+		"started_at", "completed_at", "runner_name", "runner_group_name", "labels", "html_url",
+	},
+	KindSteps: {
+		"job_id", "job_name", "run_id", "run_attempt", "number", "name", "status", "conclusion",
+		"started_at", "completed_at", "head_sha",
+	},
+}
+
+// Documents returns the documents of the given kind, built the same way they are indexed.
+func Documents(store *storage.Store, workflowID int64, kind Kind) (iter.Seq[elastic.Document], error) {
+	switch kind {
+	case KindRuns:
+		return elastic.RunDocuments(store, workflowID, nil), nil
+	case KindJobs:
+		return elastic.JobDocuments(store, workflowID, nil), nil
+	case KindSteps:
+		return elastic.StepDocuments(store, workflowID, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown document kind %q", kind)
+	}
+}
+
+// TimeField returns the field that date filters apply to for a kind of document.
+func TimeField(kind Kind) string {
+	if kind == KindRuns {
+		return "created_at"
+	}
+	return "started_at"
+}
+
+// Filter selects documents to export. Zero values match all documents.
+type Filter struct {
+	// From selects documents at or after From.
+	From time.Time
+	// To selects documents before To.
+	To time.Time
+	// Conclusions selects documents with any of these conclusions.
+	Conclusions []string
+}
+
+// Match reports whether the document of the given kind passes the filter.
+func (f Filter) Match(kind Kind, doc map[string]any) bool {
+	if len(f.Conclusions) > 0 {
+		conclusion, _ := doc["conclusion"].(string)
+		if !slices.Contains(f.Conclusions, conclusion) {
+			return false
+		}
+	}
+	if f.From.IsZero() && f.To.IsZero() {
+		return true
+	}
+
+	value, _ := doc[TimeField(kind)].(string)
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+	if !f.From.IsZero() && t.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Flatten flattens nested objects into a single level using dotted keys such
+// as head_commit.author.name. Arrays are kept as they are.
+func Flatten(doc map[string]any) map[string]any {
+	flat := make(map[string]any, len(doc))
+	flatten(flat, "", doc)
+	return flat
+}
+
+func flatten(flat map[string]any, prefix string, doc map[string]any) {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(flat, key, nested)
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// Writer writes documents to a file.
+type Writer interface {
+	Write(doc map[string]any) error
+	// Close flushes buffered documents. It does not close the underlying writer.
+	Close() error
+}
+
+// CSVWriter writes flattened documents as CSV with a header row.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+}
+
+// NewCSVWriter creates a CSV writer for the given columns.
+func NewCSVWriter(w io.Writer, columns []string) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+// Write writes the selected columns of a flattened document as a CSV record.
+func (c *CSVWriter) Write(doc map[string]any) error {
+	if !c.header {
+		if err := c.w.Write(c.columns); err != nil {
+			return fmt.Errorf("writing CSV header: %w", err)
+		}
+		c.header = true
+	}
+
+	flat := Flatten(doc)
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		value, err := formatValue(flat[column])
+		if err != nil {
+			return fmt.Errorf("formatting column %q: %w", column, err)
+		}
+		record[i] = value
+	}
+	if err := c.w.Write(record); err != nil {
+		return fmt.Errorf("writing CSV record: %w", err)
+	}
+	return nil
+}
+
+// Close writes the header if no document was written and flushes the CSV.
+func (c *CSVWriter) Close() error {
+	if !c.header {
+		if err := c.w.Write(c.columns); err != nil {
+			return fmt.Errorf("writing CSV header: %w", err)
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatValue formats a JSON value as a CSV field. Numbers are formatted
+// without exponent, arrays and objects as JSON.
+func formatValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// JSONLWriter writes documents as JSON Lines.
+type JSONLWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+// NewJSONLWriter creates a JSON Lines writer. If columns are given, only these
+// columns of the flattened document are written, otherwise the whole document.
+func NewJSONLWriter(w io.Writer, columns []string) *JSONLWriter {
+	return &JSONLWriter{enc: json.NewEncoder(w), columns: columns}
+}
+
+// Write writes a document as a single JSON line.
+func (j *JSONLWriter) Write(doc map[string]any) error {
+	var out any = doc
+	if len(j.columns) > 0 {
+		flat := Flatten(doc)
+		selected := make(map[string]any, len(j.columns))
+		for _, column := range j.columns {
+			selected[column] = flat[column]
+		}
+		out = selected
+	}
+	if err := j.enc.Encode(out); err != nil {
+		return fmt.Errorf("encoding document: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op as JSON lines are written immediately.
+func (j *JSONLWriter) Close() error {
+	return nil
+}
+
+// Result contains statistics from an export.
+type Result struct {
+	Total    int
+	Exported int
+}
+
+// Export writes the documents of the given kind from the workflows that pass
+// the filter to w.
+func Export(store *storage.Store, workflowIDs []int64, kind Kind, filter Filter, w Writer) (*Result, error) {
+	result := &Result{}
+	for _, workflowID := range workflowIDs {
+		docs, err := Documents(store, workflowID, kind)
+		if err != nil {
+			return result, err
+		}
+		for doc := range docs {
+			result.Total++
+			body, ok := doc.Body.(map[string]any)
+			if !ok || !filter.Match(kind, body) {
+				continue
+			}
+			if err := w.Write(body); err != nil {
+				return result, err
+			}
+			result.Exported++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, []string{"id", "head_commit.author.name", "labels", "missing"})
+
+	doc := map[string]any{
+		"id":          float64(1372424609),
+		"head_commit": map[string]any{"author": map[string]any{"name": "teleivo"}},
+		"labels":      []any{"ubuntu-latest"},
+	}
+	if err := w.Write(doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "id,head_commit.author.name,labels,missing\n" +
+		"1372424609,teleivo,\"[\"\"ubuntu-latest\"\"]\",\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSVWriter wrote %q, want %q", got, want)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	filter := Filter{
+		From:        time.Date(2021, 10, 12, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2021, 10, 13, 0, 0, 0, 0, time.UTC),
+		Conclusions: []string{"failure"},
+	}
+
+	tests := []struct {
+		name string
+		kind Kind
+		doc  map[string]any
+		want bool
+	}{
+		{
+			name: "run in range with conclusion",
+			kind: KindRuns,
+			doc:  map[string]any{"created_at": "2021-10-12T01:56:28Z", "conclusion": "failure"},
+			want: true,
+		},
+		{
+			name: "run with other conclusion",
+			kind: KindRuns,
+			doc:  map[string]any{"created_at": "2021-10-12T01:56:28Z", "conclusion": "success"},
+			want: false,
+		},
+		{
+			name: "job uses started_at",
+			kind: KindJobs,
+			doc:  map[string]any{"created_at": "2021-10-12T01:56:28Z", "started_at": "2021-10-13T00:00:00Z", "conclusion": "failure"},
+			want: false,
+		},
+		{
+			name: "step without time",
+			kind: KindSteps,
+			doc:  map[string]any{"conclusion": "failure"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Match(tt.kind, tt.doc); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
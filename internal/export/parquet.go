@@ -0,0 +1,255 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// RunRow is the Parquet schema of a run.
+type RunRow struct {
+	ID                  int64      `parquet:"id"`
+	WorkflowID          int64      `parquet:"workflow_id"`
+	Name                string     `parquet:"name,dict"`
+	RunNumber           int64      `parquet:"run_number"`
+	RunAttempt          int64      `parquet:"run_attempt"`
+	Event               string     `parquet:"event,dict"`
+	Status              string     `parquet:"status,dict"`
+	Conclusion          string     `parquet:"conclusion,dict"`
+	HeadBranch          string     `parquet:"head_branch"`
+	HeadSHA             string     `parquet:"head_sha"`
+	CreatedAt           *time.Time `parquet:"created_at,optional,timestamp(millisecond)"`
+	RunStartedAt        *time.Time `parquet:"run_started_at,optional,timestamp(millisecond)"`
+	UpdatedAt           *time.Time `parquet:"updated_at,optional,timestamp(millisecond)"`
+	JobsStartedAt       *time.Time `parquet:"jobs_started_at,optional,timestamp(millisecond)"`
+	JobsStartedAtName   string     `parquet:"jobs_started_at_name,dict"`
+	JobsCompletedAt     *time.Time `parquet:"jobs_completed_at,optional,timestamp(millisecond)"`
+	JobsCompletedAtName string     `parquet:"jobs_completed_at_name,dict"`
+	HTMLURL             string     `parquet:"html_url"`
+}
+
+// JobRow is the Parquet schema of a job.
+type JobRow struct {
+	ID              int64      `parquet:"id"`
+	RunID           int64      `parquet:"run_id"`
+	RunAttempt      int64      `parquet:"run_attempt"`
+	Name            string     `parquet:"name,dict"`
+	Status          string     `parquet:"status,dict"`
+	Conclusion      string     `parquet:"conclusion,dict"`
+	HeadSHA         string     `parquet:"head_sha"`
+	StartedAt       *time.Time `parquet:"started_at,optional,timestamp(millisecond)"`
+	CompletedAt     *time.Time `parquet:"completed_at,optional,timestamp(millisecond)"`
+	RunnerName      string     `parquet:"runner_name,dict"`
+	RunnerGroupName string     `parquet:"runner_group_name,dict"`
+	Labels          []string   `parquet:"labels,list"`
+	HTMLURL         string     `parquet:"html_url"`
+}
+
+// StepRow is the Parquet schema of a step.
+type StepRow struct {
+	JobID       int64      `parquet:"job_id"`
+	JobName     string     `parquet:"job_name,dict"`
+	RunID       int64      `parquet:"run_id"`
+	RunAttempt  int64      `parquet:"run_attempt"`
+	Number      int64      `parquet:"number"`
+	Name        string     `parquet:"name,dict"`
+	Status      string     `parquet:"status,dict"`
+	Conclusion  string     `parquet:"conclusion,dict"`
+	StartedAt   *time.Time `parquet:"started_at,optional,timestamp(millisecond)"`
+	CompletedAt *time.Time `parquet:"completed_at,optional,timestamp(millisecond)"`
+	HeadSHA     string     `parquet:"head_sha"`
+}
+
+func runRow(doc map[string]any) RunRow {
+	return RunRow{
+		ID:                  intField(doc, "id"),
+		WorkflowID:          intField(doc, "workflow_id"),
+		Name:                stringField(doc, "name"),
+		RunNumber:           intField(doc, "run_number"),
+		RunAttempt:          intField(doc, "run_attempt"),
+		Event:               stringField(doc, "event"),
+		Status:              stringField(doc, "status"),
+		Conclusion:          stringField(doc, "conclusion"),
+		HeadBranch:          stringField(doc, "head_branch"),
+		HeadSHA:             stringField(doc, "head_sha"),
+		CreatedAt:           timeField(doc, "created_at"),
+		RunStartedAt:        timeField(doc, "run_started_at"),
+		UpdatedAt:           timeField(doc, "updated_at"),
+		JobsStartedAt:       timeField(doc, "jobs_started_at"),
+		JobsStartedAtName:   stringField(doc, "jobs_started_at_name"),
+		JobsCompletedAt:     timeField(doc, "jobs_completed_at"),
+		JobsCompletedAtName: stringField(doc, "jobs_completed_at_name"),
+		HTMLURL:             stringField(doc, "html_url"),
+	}
+}
+
+func jobRow(doc map[string]any) JobRow {
+	var labels []string
+	if values, ok := doc["labels"].([]any); ok {
+		for _, v := range values {
+			if label, ok := v.(string); ok {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return JobRow{
+		ID:              intField(doc, "id"),
+		RunID:           intField(doc, "run_id"),
+		RunAttempt:      intField(doc, "run_attempt"),
+		Name:            stringField(doc, "name"),
+		Status:          stringField(doc, "status"),
+		Conclusion:      stringField(doc, "conclusion"),
+		HeadSHA:         stringField(doc, "head_sha"),
+		StartedAt:       timeField(doc, "started_at"),
+		CompletedAt:     timeField(doc, "completed_at"),
+		RunnerName:      stringField(doc, "runner_name"),
+		RunnerGroupName: stringField(doc, "runner_group_name"),
+		Labels:          labels,
+		HTMLURL:         stringField(doc, "html_url"),
+	}
+}
+
+func stepRow(doc map[string]any) StepRow {
+	return StepRow{
+		JobID:       intField(doc, "job_id"),
+		JobName:     stringField(doc, "job_name"),
+		RunID:       intField(doc, "run_id"),
+		RunAttempt:  intField(doc, "run_attempt"),
+		Number:      intField(doc, "number"),
+		Name:        stringField(doc, "name"),
+		Status:      stringField(doc, "status"),
+		Conclusion:  stringField(doc, "conclusion"),
+		StartedAt:   timeField(doc, "started_at"),
+		CompletedAt: timeField(doc, "completed_at"),
+		HeadSHA:     stringField(doc, "head_sha"),
+	}
+}
+
+func stringField(doc map[string]any, key string) string {
+	v, _ := doc[key].(string)
+	return v
+}
+
+func intField(doc map[string]any, key string) int64 {
+	switch v := doc[key].(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	default:
+		return 0
+	}
+}
+
+func timeField(doc map[string]any, key string) *time.Time {
+	v, ok := doc[key].(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// ExportParquet writes the documents of the given kind from the workflows
+// that pass the filter as Parquet files into dir. Files are partitioned by
+// workflow and by the month of the document time field using the layout
+// <kind>/workflow_id=<id>/month=<yyyy-mm>/part-0.parquet.
+func ExportParquet(store *storage.Store, workflowIDs []int64, kind Kind, filter Filter, dir string) (*Result, error) {
+	switch kind {
+	case KindRuns:
+		return exportParquet(store, workflowIDs, kind, filter, dir, runRow)
+	case KindJobs:
+		return exportParquet(store, workflowIDs, kind, filter, dir, jobRow)
+	case KindSteps:
+		return exportParquet(store, workflowIDs, kind, filter, dir, stepRow)
+	default:
+		return nil, fmt.Errorf("unknown document kind %q", kind)
+	}
+}
+
+func exportParquet[T any](store *storage.Store, workflowIDs []int64, kind Kind, filter Filter, dir string, row func(map[string]any) T) (*Result, error) {
+	result := &Result{}
+	for _, workflowID := range workflowIDs {
+		docs, err := Documents(store, workflowID, kind)
+		if err != nil {
+			return result, err
+		}
+
+		pw := &partitionWriter[T]{
+			dir:     filepath.Join(dir, string(kind), "workflow_id="+strconv.FormatInt(workflowID, 10)),
+			files:   make(map[string]*os.File),
+			writers: make(map[string]*parquet.GenericWriter[T]),
+		}
+		for doc := range docs {
+			result.Total++
+			body, ok := doc.Body.(map[string]any)
+			if !ok || !filter.Match(kind, body) {
+				continue
+			}
+
+			month := "unknown"
+			if t := timeField(body, TimeField(kind)); t != nil {
+				month = t.UTC().Format("2006-01")
+			}
+			if err := pw.write(month, row(body)); err != nil {
+				_ = pw.close()
+				return result, err
+			}
+			result.Exported++
+		}
+		if err := pw.close(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// partitionWriter writes rows into one Parquet file per month.
+type partitionWriter[T any] struct {
+	dir     string
+	files   map[string]*os.File
+	writers map[string]*parquet.GenericWriter[T]
+}
+
+func (p *partitionWriter[T]) write(month string, row T) error {
+	w, ok := p.writers[month]
+	if !ok {
+		dir := filepath.Join(p.dir, "month="+month)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("creating directory %q: %w", dir, err)
+		}
+		path := filepath.Join(dir, "part-0.parquet")
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("creating parquet file: %w", err)
+		}
+		w = parquet.NewGenericWriter[T](f)
+		p.files[month] = f
+		p.writers[month] = w
+	}
+	if _, err := w.Write([]T{row}); err != nil {
+		return fmt.Errorf("writing parquet row: %w", err)
+	}
+	return nil
+}
+
+func (p *partitionWriter[T]) close() error {
+	var firstErr error
+	for month, w := range p.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing parquet writer: %w", err)
+		}
+		if err := p.files[month].Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing parquet file: %w", err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestExportParquet(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for runID, data := range map[int64]string{
+		1: `{"id":1,"workflow_id":10954,"created_at":"2021-10-12T01:56:28Z","conclusion":"success"}`,
+		2: `{"id":2,"workflow_id":10954,"created_at":"2021-10-30T10:00:00Z","conclusion":"failure"}`,
+		3: `{"id":3,"workflow_id":10954,"created_at":"2021-11-01T08:00:00Z","conclusion":"success"}`,
+	} {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SaveJobs(10954, 1, []byte(`{"jobs":[{"id":7,"run_id":1,"started_at":"2021-10-12T01:57:00Z","labels":["ubuntu-latest"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	result, err := ExportParquet(store, []int64{10954}, KindRuns, Filter{}, dir)
+	if err != nil {
+		t.Fatalf("ExportParquet() error = %v", err)
+	}
+	if result.Total != 3 || result.Exported != 3 {
+		t.Errorf("ExportParquet() = %+v, want 3 exported", result)
+	}
+
+	runs := filepath.Join(dir, "runs", "workflow_id=10954")
+	october, err := parquet.ReadFile[RunRow](filepath.Join(runs, "month=2021-10", "part-0.parquet"))
+	if err != nil {
+		t.Fatalf("reading October runs: %v", err)
+	}
+	var ids []int64
+	for _, row := range october {
+		ids = append(ids, row.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{1, 2}) {
+		t.Errorf("October runs = %v, want [1 2]", ids)
+	}
+	november, err := parquet.ReadFile[RunRow](filepath.Join(runs, "month=2021-11", "part-0.parquet"))
+	if err != nil {
+		t.Fatalf("reading November runs: %v", err)
+	}
+	if len(november) != 1 || november[0].ID != 3 || november[0].WorkflowID != 10954 || november[0].Conclusion != "success" {
+		t.Errorf("November runs = %+v, want run 3", november)
+	}
+	want := time.Date(2021, 11, 1, 8, 0, 0, 0, time.UTC)
+	if got := november[0].CreatedAt; got == nil || !got.Equal(want) {
+		t.Errorf("November run created_at = %v, want %v", got, want)
+	}
+	if november[0].RunStartedAt != nil {
+		t.Errorf("November run run_started_at = %v, want null", november[0].RunStartedAt)
+	}
+
+	schema := openParquetSchema(t, filepath.Join(runs, "month=2021-11", "part-0.parquet"))
+	for _, column := range []string{"id", "workflow_id", "run_number"} {
+		leaf, ok := schema.Lookup(column)
+		if !ok {
+			t.Fatalf("column %q not found", column)
+		}
+		if kind := leaf.Node.Type().Kind(); kind != parquet.Int64 {
+			t.Errorf("column %q is of kind %s, want INT64", column, kind)
+		}
+	}
+	leaf, ok := schema.Lookup("created_at")
+	if !ok {
+		t.Fatal("column created_at not found")
+	}
+	if _, ok := leaf.Node.Type().LogicalType().Value.(*format.TimestampType); !ok {
+		t.Errorf("column created_at has logical type %s, want a timestamp", leaf.Node.Type().LogicalType())
+	}
+
+	if _, err := ExportParquet(store, []int64{10954}, KindJobs, Filter{}, dir); err != nil {
+		t.Fatalf("ExportParquet() jobs error = %v", err)
+	}
+	jobs, err := parquet.ReadFile[JobRow](filepath.Join(dir, "jobs", "workflow_id=10954", "month=2021-10", "part-0.parquet"))
+	if err != nil {
+		t.Fatalf("reading jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != 7 || jobs[0].RunID != 1 || !slices.Equal(jobs[0].Labels, []string{"ubuntu-latest"}) {
+		t.Errorf("jobs = %+v, want job 7 of run 1", jobs)
+	}
+}
+
+func openParquetSchema(t *testing.T, path string) *parquet.Schema {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatalf("opening parquet file: %v", err)
+	}
+	return file.Schema()
+}
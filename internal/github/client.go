@@ -75,6 +75,11 @@ func (c *Client) Actions() *github.ActionsService {
 	return c.client.Actions
 }
 
+// Repositories returns the Repositories service for accessing repository contents.
+func (c *Client) Repositories() *github.RepositoriesService {
+	return c.client.Repositories
+}
+
 // RateLimits returns current rate limit status.
 func (c *Client) RateLimits(ctx context.Context) (*github.RateLimits, error) {
 	limits, _, err := c.client.RateLimit.Get(ctx)
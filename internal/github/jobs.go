@@ -13,7 +13,7 @@ import (
 // FetchJobs fetches jobs for the given run IDs and stores them.
 func FetchJobs(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store, runIDs []int64) error {
 	for _, runID := range runIDs {
-		if err := fetchJobsForRun(ctx, client, owner, repo, workflowID, store, runID); err != nil {
+		if err := FetchRunJobs(ctx, client, owner, repo, workflowID, store, runID); err != nil {
 			slog.Warn("failed to fetch jobs for run", "run_id", runID, "error", err)
 		}
 	}
@@ -36,7 +36,9 @@ func FetchStoredRunJobs(ctx context.Context, client *Client, owner, repo string,
 	return FetchJobs(ctx, client, owner, repo, workflowID, store, runIDs)
 }
 
-func fetchJobsForRun(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store, runID int64) error {
+// FetchRunJobs fetches all jobs of a run and stores them. Stored jobs of the
+// run are only replaced once all jobs were fetched.
+func FetchRunJobs(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store, runID int64) error {
 	slog.Debug("fetching jobs for run", "run_id", runID)
 
 	opts := &github.ListWorkflowJobsOptions{
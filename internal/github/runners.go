@@ -0,0 +1,65 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// RunnersSnapshot is the stored snapshot of the self-hosted runners of a
+// repository or organization.
+type RunnersSnapshot struct {
+	Owner     string           `json:"owner"`
+	Repo      string           `json:"repo,omitempty"`
+	FetchedAt time.Time        `json:"fetched_at"`
+	Runners   []*github.Runner `json:"runners"`
+}
+
+// FetchRunners fetches the self-hosted runners of a repository, or of an
+// organization if repo is empty, and stores them as a snapshot replacing the
+// previous one. Listing runners requires admin access.
+func FetchRunners(ctx context.Context, client *Client, owner, repo string, store *storage.Store) error {
+	opts := &github.ListRunnersOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	snapshot := RunnersSnapshot{Owner: owner, Repo: repo, FetchedAt: time.Now().UTC()}
+	for {
+		var runners *github.Runners
+		var resp *github.Response
+		var err error
+		if repo == "" {
+			runners, resp, err = client.Actions().ListOrganizationRunners(ctx, owner, opts)
+		} else {
+			runners, resp, err = client.Actions().ListRunners(ctx, owner, repo, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("listing runners: %w", err)
+		}
+
+		snapshot.Runners = append(snapshot.Runners, runners.Runners...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshaling runners: %w", err)
+	}
+	if err := store.SaveRunners(owner, repo, data); err != nil {
+		return fmt.Errorf("saving runners: %w", err)
+	}
+
+	slog.Info("fetched runners", "owner", owner, "repo", repo, "runner_count", len(snapshot.Runners))
+	return nil
+}
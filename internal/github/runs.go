@@ -69,3 +69,22 @@ func FetchRuns(ctx context.Context, client *Client, owner, repo string, workflow
 
 	return fetchedRunIDs, nil
 }
+
+// FetchRun fetches a single workflow run from GitHub and stores it locally,
+// overwriting a run that is already stored.
+func FetchRun(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store, runID int64) error {
+	run, _, err := client.Actions().GetWorkflowRunByID(ctx, owner, repo, runID)
+	if err != nil {
+		return fmt.Errorf("getting workflow run #%d: %w", runID, err)
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("marshaling run #%d: %w", runID, err)
+	}
+
+	if err := store.SaveRun(workflowID, runID, data); err != nil {
+		return fmt.Errorf("saving run #%d: %w", runID, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// commitSHAPattern matches a full commit SHA.
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// FetchWorkflowFiles fetches the workflow file of each stored run at the
+// commit the run ran on and stores each distinct version once. Commits whose
+// workflow file is already stored are skipped.
+func FetchWorkflowFiles(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store) error {
+	var fetched, skipped int
+	seen := make(map[string]bool)
+	for data, err := range store.IterRuns(workflowID) {
+		if err != nil {
+			slog.Warn("error reading run", "error", err)
+			continue
+		}
+
+		var run struct {
+			ID      int64  `json:"id"`
+			HeadSHA string `json:"head_sha"`
+			Path    string `json:"path"`
+		}
+		if err := json.Unmarshal(data, &run); err != nil {
+			slog.Warn("error unmarshaling run", "error", err)
+			continue
+		}
+		if !commitSHAPattern.MatchString(run.HeadSHA) || run.Path == "" {
+			slog.Debug("run has no workflow file", "run_id", run.ID)
+			continue
+		}
+		if seen[run.HeadSHA] {
+			continue
+		}
+		seen[run.HeadSHA] = true
+
+		hash, err := store.WorkflowFileHash(workflowID, run.HeadSHA)
+		if err != nil {
+			return err
+		}
+		if hash != "" {
+			skipped++
+			continue
+		}
+
+		if err := fetchWorkflowFile(ctx, client, owner, repo, workflowID, store, run.HeadSHA, run.Path); err != nil {
+			slog.Warn("failed to fetch workflow file", "run_id", run.ID, "head_sha", run.HeadSHA, "path", run.Path, "error", err)
+			continue
+		}
+		fetched++
+	}
+
+	slog.Info("fetched workflow files", "fetched", fetched, "already_stored", skipped)
+	return nil
+}
+
+func fetchWorkflowFile(ctx context.Context, client *Client, owner, repo string, workflowID int64, store *storage.Store, headSHA, path string) error {
+	// paths of runs of reusable workflows can carry the ref they were called at
+	path, _, _ = strings.Cut(path, "@")
+
+	file, _, _, err := client.Repositories().GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: headSHA})
+	if err != nil {
+		return fmt.Errorf("getting contents of %q: %w", path, err)
+	}
+	if file == nil {
+		return fmt.Errorf("%q is not a file", path)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return fmt.Errorf("decoding contents of %q: %w", path, err)
+	}
+
+	hash, err := store.SaveWorkflowFile(workflowID, headSHA, []byte(content))
+	if err != nil {
+		return fmt.Errorf("saving workflow file: %w", err)
+	}
+	slog.Debug("stored workflow file", "head_sha", headSHA, "path", path, "hash", hash)
+	return nil
+}
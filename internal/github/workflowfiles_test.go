@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestFetchWorkflowFiles(t *testing.T) {
+	const (
+		sha1 = "0d2e4c8b4f3a1e6d9c7b5a3f1e0d2c4b6a8f9e7d"
+		sha2 = "9f8e7d6c5b4a39281706f5e4d3c2b1a098765432"
+		sha3 = "1111111111111111111111111111111111111111"
+	)
+	content := "name: CI\non: push\n"
+
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/dhis2/dhis2-core/contents/.github/workflows/ci.yml" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		requests[r.URL.Query().Get("ref")]++
+		_, _ = w.Write([]byte(`{"type":"file","encoding":"base64","content":"` + base64.StdEncoding.EncodeToString([]byte(content)) + `"}`))
+	}))
+	defer server.Close()
+
+	gh := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh.BaseURL = baseURL
+	client := &Client{client: gh}
+
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for runID, data := range map[int64]string{
+		1: `{"id":1,"head_sha":"` + sha1 + `","path":".github/workflows/ci.yml"}`,
+		2: `{"id":2,"head_sha":"` + sha1 + `","path":".github/workflows/ci.yml"}`,
+		3: `{"id":3,"head_sha":"` + sha2 + `","path":".github/workflows/ci.yml@refs/heads/master"}`,
+		4: `{"id":4,"head_sha":"` + sha3 + `","path":".github/workflows/ci.yml"}`,
+		5: `{"id":5,"head_sha":"master","path":".github/workflows/ci.yml"}`,
+	} {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, err := store.SaveWorkflowFile(10954, sha3, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := FetchWorkflowFiles(context.Background(), client, "dhis2", "dhis2-core", 10954, store); err != nil {
+		t.Fatalf("FetchWorkflowFiles() error = %v", err)
+	}
+
+	want := map[string]int{sha1: 1, sha2: 1}
+	if len(requests) != len(want) || requests[sha1] != 1 || requests[sha2] != 1 {
+		t.Errorf("FetchWorkflowFiles() requested refs %v, want %v", requests, want)
+	}
+	for _, sha := range []string{sha1, sha2} {
+		hash, err := store.WorkflowFileHash(10954, sha)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != stored {
+			t.Errorf("WorkflowFileHash(%q) = %q, want %q", sha, hash, stored)
+		}
+	}
+}
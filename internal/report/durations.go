@@ -0,0 +1,262 @@
+// Package report summarizes the stored GitHub Actions data without
+// Elasticsearch.
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/export"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+// Groupings of durations.
+const (
+	GroupByWorkflow = "workflow"
+	GroupByJob      = "job"
+	GroupByStep     = "step"
+	GroupByBranch   = "branch"
+	GroupByWeek     = "week"
+)
+
+// Formats of the report.
+const (
+	FormatTable    = "table"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+)
+
+// DurationStats summarizes the durations of the documents sharing a key.
+type DurationStats struct {
+	// Key is the workflow name, job name, step name, branch or ISO week like
+	// 2021-W42 depending on the grouping.
+	Key   string
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P75   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// DurationsOptions configures the Durations operation.
+type DurationsOptions struct {
+	// Kind selects the durations of runs, jobs or steps. Runs last from the
+	// start of their first job to the completion of their last job.
+	Kind    export.Kind
+	GroupBy string
+	Filter  export.Filter
+}
+
+// ValidateGrouping returns an error if documents of the kind cannot be grouped by groupBy.
+func ValidateGrouping(kind export.Kind, groupBy string) error {
+	switch groupBy {
+	case GroupByWorkflow, GroupByBranch, GroupByWeek:
+		return nil
+	case GroupByJob:
+		if kind != export.KindRuns {
+			return nil
+		}
+	case GroupByStep:
+		if kind == export.KindSteps {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown grouping %q", groupBy)
+	}
+	return fmt.Errorf("%s cannot be grouped by %s", kind, groupBy)
+}
+
+// Durations computes duration statistics of the stored runs, jobs or steps of
+// the given workflows. Documents that did not complete are skipped.
+func Durations(store *storage.Store, workflowIDs []int64, opts DurationsOptions) ([]DurationStats, error) {
+	if err := ValidateGrouping(opts.Kind, opts.GroupBy); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]time.Duration)
+	for _, workflowID := range workflowIDs {
+		docs, err := export.Documents(store, workflowID, opts.Kind)
+		if err != nil {
+			return nil, err
+		}
+		for doc := range docs {
+			body, ok := doc.Body.(map[string]any)
+			if !ok || !opts.Filter.Match(opts.Kind, body) {
+				continue
+			}
+			start, end := "started_at", "completed_at"
+			if opts.Kind == export.KindRuns {
+				start, end = "jobs_started_at", "jobs_completed_at"
+			}
+			started, err := time.Parse(time.RFC3339, stringField(body, start))
+			if err != nil {
+				continue
+			}
+			completed, err := time.Parse(time.RFC3339, stringField(body, end))
+			if err != nil || completed.Before(started) {
+				continue
+			}
+			key := groupKey(opts.Kind, opts.GroupBy, workflowID, body, started)
+			groups[key] = append(groups[key], completed.Sub(started))
+		}
+	}
+
+	stats := make([]DurationStats, 0, len(groups))
+	for key, durations := range groups {
+		stats = append(stats, summarize(key, durations))
+	}
+	slices.SortFunc(stats, func(a, b DurationStats) int {
+		if opts.GroupBy == GroupByWeek {
+			return strings.Compare(a.Key, b.Key)
+		}
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Key, b.Key))
+	})
+	return stats, nil
+}
+
+// groupKey returns the key of the group a document belongs to.
+func groupKey(kind export.Kind, groupBy string, workflowID int64, doc map[string]any, started time.Time) string {
+	switch groupBy {
+	case GroupByWorkflow:
+		name := "name"
+		if kind != export.KindRuns {
+			name = "run_name"
+		}
+		return cmp.Or(stringField(doc, name), strconv.FormatInt(workflowID, 10))
+	case GroupByJob:
+		if kind == export.KindSteps {
+			return stringField(doc, "job_name")
+		}
+		return stringField(doc, "name")
+	case GroupByStep:
+		return stringField(doc, "name")
+	case GroupByBranch:
+		if kind == export.KindRuns {
+			return stringField(doc, "head_branch")
+		}
+		return stringField(doc, "run_head_branch")
+	case GroupByWeek:
+		year, week := started.UTC().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return ""
+}
+
+// summarize computes the statistics of the durations of a group.
+func summarize(key string, durations []time.Duration) DurationStats {
+	slices.Sort(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return DurationStats{
+		Key:   key,
+		Count: len(durations),
+		Mean:  total / time.Duration(len(durations)),
+		P50:   percentile(durations, 0.5),
+		P75:   percentile(durations, 0.75),
+		P90:   percentile(durations, 0.9),
+		P99:   percentile(durations, 0.99),
+	}
+}
+
+// percentile returns the p-th percentile of the sorted durations,
+// interpolating linearly between the closest ranks.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return sorted[lower] + time.Duration(fraction*float64(sorted[upper]-sorted[lower]))
+}
+
+// stringField returns the string value of a field or an empty string.
+func stringField(doc map[string]any, key string) string {
+	value, _ := doc[key].(string)
+	return value
+}
+
+// WriteDurations writes the duration statistics in the given format. The
+// groupBy names the key column. JSON and CSV give durations in seconds.
+func WriteDurations(w io.Writer, format, groupBy string, stats []DurationStats) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "%s\tCOUNT\tMEAN\tP50\tP75\tP90\tP99\n", strings.ToUpper(groupBy))
+		for _, s := range stats {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", s.Key, s.Count,
+				formatDuration(s.Mean), formatDuration(s.P50), formatDuration(s.P75), formatDuration(s.P90), formatDuration(s.P99))
+		}
+		return tw.Flush()
+	case FormatMarkdown:
+		if _, err := fmt.Fprintf(w, "| %s | count | mean | p50 | p75 | p90 | p99 |\n|---|--:|--:|--:|--:|--:|--:|\n", groupBy); err != nil {
+			return err
+		}
+		for _, s := range stats {
+			key := strings.ReplaceAll(s.Key, "|", `\|`)
+			if _, err := fmt.Fprintf(w, "| %s | %d | %s | %s | %s | %s | %s |\n", key, s.Count,
+				formatDuration(s.Mean), formatDuration(s.P50), formatDuration(s.P75), formatDuration(s.P90), formatDuration(s.P99)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		type row struct {
+			Key         string  `json:"key"`
+			Count       int     `json:"count"`
+			MeanSeconds float64 `json:"mean_seconds"`
+			P50Seconds  float64 `json:"p50_seconds"`
+			P75Seconds  float64 `json:"p75_seconds"`
+			P90Seconds  float64 `json:"p90_seconds"`
+			P99Seconds  float64 `json:"p99_seconds"`
+		}
+		rows := make([]row, len(stats))
+		for i, s := range stats {
+			rows[i] = row{s.Key, s.Count, seconds(s.Mean), seconds(s.P50), seconds(s.P75), seconds(s.P90), seconds(s.P99)}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{groupBy, "count", "mean_seconds", "p50_seconds", "p75_seconds", "p90_seconds", "p99_seconds"}); err != nil {
+			return fmt.Errorf("writing CSV header: %w", err)
+		}
+		for _, s := range stats {
+			record := []string{s.Key, strconv.Itoa(s.Count)}
+			for _, d := range []time.Duration{s.Mean, s.P50, s.P75, s.P90, s.P99} {
+				record = append(record, strconv.FormatFloat(seconds(d), 'f', -1, 64))
+			}
+			if err := cw.Write(record); err != nil {
+				return fmt.Errorf("writing CSV record: %w", err)
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// formatDuration formats a duration rounded to seconds like 12m30s.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// seconds returns the duration in seconds rounded to a tenth of a second.
+func seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*10) / 10
+}
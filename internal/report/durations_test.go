@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/teleivo/github-action-metrics/internal/export"
+	"github.com/teleivo/github-action-metrics/internal/storage"
+)
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{1 * time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 10 * time.Minute}
+
+	tests := map[float64]time.Duration{
+		0.5:  3 * time.Minute,
+		0.75: 4 * time.Minute,
+		0.9:  7*time.Minute + 36*time.Second,
+		0.99: 9*time.Minute + 45*time.Second + 600*time.Millisecond,
+	}
+	for p, want := range tests {
+		if got := percentile(durations, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := map[int64]string{
+		1: `{"id":1,"name":"Test","head_branch":"master","created_at":"2021-10-18T10:00:00Z"}`,
+		2: `{"id":2,"name":"Test","head_branch":"TECH-699","created_at":"2021-10-25T10:00:00Z"}`,
+	}
+	jobs := map[int64]string{
+		1: `{"jobs":[
+			{"id":11,"run_id":1,"name":"build","started_at":"2021-10-18T10:00:00Z","completed_at":"2021-10-18T10:05:00Z"},
+			{"id":12,"run_id":1,"name":"test","started_at":"2021-10-18T10:05:00Z","completed_at":"2021-10-18T10:15:00Z"}
+		]}`,
+		2: `{"jobs":[
+			{"id":21,"run_id":2,"name":"build","started_at":"2021-10-25T10:00:00Z","completed_at":"2021-10-25T10:07:00Z"},
+			{"id":22,"run_id":2,"name":"test","started_at":"2021-10-25T10:07:00Z"}
+		]}`,
+	}
+	for runID, data := range runs {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveJobs(10954, runID, []byte(jobs[runID])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts DurationsOptions
+		want []DurationStats
+	}{
+		{
+			name: "runs by workflow",
+			opts: DurationsOptions{Kind: export.KindRuns, GroupBy: GroupByWorkflow},
+			want: []DurationStats{
+				{Key: "Test", Count: 2, Mean: 11 * time.Minute, P50: 11 * time.Minute, P75: 13 * time.Minute, P90: 14*time.Minute + 12*time.Second, P99: 14*time.Minute + 55*time.Second + 200*time.Millisecond},
+			},
+		},
+		{
+			name: "jobs by week skip incomplete jobs",
+			opts: DurationsOptions{Kind: export.KindJobs, GroupBy: GroupByWeek},
+			want: []DurationStats{
+				{Key: "2021-W42", Count: 2, Mean: 7*time.Minute + 30*time.Second, P50: 7*time.Minute + 30*time.Second, P75: 8*time.Minute + 45*time.Second, P90: 9*time.Minute + 30*time.Second, P99: 9*time.Minute + 57*time.Second},
+				{Key: "2021-W43", Count: 1, Mean: 7 * time.Minute, P50: 7 * time.Minute, P75: 7 * time.Minute, P90: 7 * time.Minute, P99: 7 * time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Durations(store, []int64{10954}, tt.opts)
+			if err != nil {
+				t.Fatalf("Durations() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Durations() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Durations()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := Durations(store, []int64{10954}, DurationsOptions{Kind: export.KindRuns, GroupBy: GroupByStep}); err == nil {
+		t.Error("Durations() expected error grouping runs by step")
+	}
+}
+
+func TestWriteDurationsMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	stats := []DurationStats{{Key: "test | unit", Count: 3, Mean: 90 * time.Second, P50: time.Minute, P75: 2 * time.Minute, P90: 2 * time.Minute, P99: 2 * time.Minute}}
+	if err := WriteDurations(&buf, FormatMarkdown, GroupByJob, stats); err != nil {
+		t.Fatalf("WriteDurations() error = %v", err)
+	}
+
+	want := "| job | count | mean | p50 | p75 | p90 | p99 |\n" +
+		"|---|--:|--:|--:|--:|--:|--:|\n" +
+		"| test \\| unit | 3 | 1m30s | 1m0s | 2m0s | 2m0s | 2m0s |\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDurations() wrote %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,450 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BundleFormat is the file format of an exported bundle.
+type BundleFormat string
+
+// Supported bundle formats.
+const (
+	BundleTarGz  BundleFormat = "tar.gz"
+	BundleNDJSON BundleFormat = "ndjson"
+)
+
+// bundleVersion is the version of the bundle layout written by Export.
+const bundleVersion = 1
+
+const manifestName = "manifest.json"
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	Version   int                `json:"version"`
+	CreatedAt time.Time          `json:"created_at"`
+	From      *time.Time         `json:"from,omitempty"`
+	To        *time.Time         `json:"to,omitempty"`
+	Workflows []ManifestWorkflow `json:"workflows"`
+}
+
+// ManifestWorkflow describes the contents of a bundle for a single workflow.
+type ManifestWorkflow struct {
+	WorkflowID int64 `json:"workflow_id"`
+	Runs       int   `json:"runs"`
+	Jobs       int   `json:"jobs"`
+}
+
+// ExportOptions configures which runs Export writes.
+type ExportOptions struct {
+	// WorkflowIDs restricts the export to these workflows. Defaults to all workflows.
+	WorkflowIDs []int64
+	// From restricts the export to runs created at or after From.
+	From time.Time
+	// To restricts the export to runs created before To.
+	To     time.Time
+	Format BundleFormat
+}
+
+// ImportOptions configures how Import merges a bundle into the store.
+type ImportOptions struct {
+	// Overwrite replaces runs and jobs that are already stored instead of skipping them.
+	Overwrite bool
+}
+
+// ImportResult contains statistics from an import.
+type ImportResult struct {
+	Runs        int `json:"runs"`
+	Jobs        int `json:"jobs"`
+	SkippedRuns int `json:"skipped_runs"`
+	SkippedJobs int `json:"skipped_jobs"`
+}
+
+// ndjsonRecord is a single line of an NDJSON bundle.
+type ndjsonRecord struct {
+	Type       string          `json:"type"`
+	Manifest   *Manifest       `json:"manifest,omitempty"`
+	WorkflowID int64           `json:"workflow_id,omitempty"`
+	RunID      int64           `json:"run_id,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// Export writes the selected runs and their jobs to w as a single bundle
+// starting with a manifest. Runs and jobs with invalid JSON are skipped and
+// not counted in the manifest.
+func (s *Store) Export(w io.Writer, opts ExportOptions) (*Manifest, error) {
+	workflowIDs := opts.WorkflowIDs
+	if len(workflowIDs) == 0 {
+		ids, err := s.ListWorkflowIDs()
+		if err != nil {
+			return nil, err
+		}
+		workflowIDs = ids
+	}
+
+	manifest := &Manifest{
+		Version:   bundleVersion,
+		CreatedAt: time.Now().UTC(),
+		Workflows: []ManifestWorkflow{},
+	}
+	if !opts.From.IsZero() {
+		manifest.From = &opts.From
+	}
+	if !opts.To.IsZero() {
+		manifest.To = &opts.To
+	}
+
+	// select runs and validate their jobs up front so the manifest can lead
+	// the bundle and count what is written. Runs with invalid JSON are
+	// skipped by loadRunSummaries.
+	selected := make(map[int64][]exportRun)
+	for _, workflowID := range workflowIDs {
+		runs, err := s.loadRunSummaries(workflowID)
+		if err != nil {
+			return nil, err
+		}
+		entry := ManifestWorkflow{WorkflowID: workflowID}
+		for _, run := range runs {
+			if !opts.From.IsZero() && run.CreatedAt.Before(opts.From) {
+				continue
+			}
+			if !opts.To.IsZero() && !run.CreatedAt.Before(opts.To) {
+				continue
+			}
+			jobs, err := s.exportableJobs(workflowID, run.ID)
+			if err != nil {
+				return nil, err
+			}
+			selected[workflowID] = append(selected[workflowID], exportRun{id: run.ID, jobs: jobs})
+			entry.Runs++
+			if jobs {
+				entry.Jobs++
+			}
+		}
+		manifest.Workflows = append(manifest.Workflows, entry)
+	}
+
+	bw, err := newBundleWriter(w, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	if err := bw.writeManifest(manifest); err != nil {
+		return nil, err
+	}
+	for _, workflowID := range workflowIDs {
+		for _, run := range selected[workflowID] {
+			data, err := s.LoadRun(workflowID, run.id)
+			if err != nil {
+				return nil, err
+			}
+			if !json.Valid(data) {
+				return nil, fmt.Errorf("run #%d changed while exporting and is not valid JSON", run.id)
+			}
+			if err := bw.writeFile("runs", workflowID, run.id, data); err != nil {
+				return nil, err
+			}
+
+			if !run.jobs {
+				continue
+			}
+			data, err = s.LoadJobs(workflowID, run.id)
+			if err != nil {
+				return nil, err
+			}
+			if !json.Valid(data) {
+				return nil, fmt.Errorf("jobs of run #%d changed while exporting and are not valid JSON", run.id)
+			}
+			if err := bw.writeFile("jobs", workflowID, run.id, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportRun is a run selected for export.
+type exportRun struct {
+	id int64
+	// jobs is true if the jobs of the run are exported.
+	jobs bool
+}
+
+// exportableJobs returns true if the jobs of a run are stored and valid JSON.
+func (s *Store) exportableJobs(workflowID, runID int64) (bool, error) {
+	if !s.JobExists(workflowID, runID) {
+		return false, nil
+	}
+	data, err := s.LoadJobs(workflowID, runID)
+	if err != nil {
+		return false, err
+	}
+	if !json.Valid(data) {
+		slog.Warn("skipping jobs with invalid JSON", "workflow_id", workflowID, "run_id", runID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// bundleWriter writes the entries of a bundle in a particular format.
+type bundleWriter interface {
+	writeManifest(m *Manifest) error
+	// writeFile writes a run or jobs payload. kind is either runs or jobs.
+	writeFile(kind string, workflowID, runID int64, data []byte) error
+	Close() error
+}
+
+func newBundleWriter(w io.Writer, format BundleFormat) (bundleWriter, error) {
+	switch format {
+	case BundleTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarBundleWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+	case BundleNDJSON:
+		return &ndjsonBundleWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", format)
+	}
+}
+
+type tarBundleWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (b *tarBundleWriter) writeManifest(m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	return b.writeEntry(manifestName, data)
+}
+
+func (b *tarBundleWriter) writeFile(kind string, workflowID, runID int64, data []byte) error {
+	name := path.Join("workflows", strconv.FormatInt(workflowID, 10), kind, strconv.FormatInt(runID, 10)+".json")
+	return b.writeEntry(name, data)
+}
+
+func (b *tarBundleWriter) writeEntry(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %q: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("writing %q: %w", name, err)
+	}
+	return nil
+}
+
+func (b *tarBundleWriter) Close() error {
+	if err := b.tw.Close(); err != nil {
+		return fmt.Errorf("closing tar: %w", err)
+	}
+	if err := b.gz.Close(); err != nil {
+		return fmt.Errorf("closing gzip: %w", err)
+	}
+	return nil
+}
+
+type ndjsonBundleWriter struct {
+	enc *json.Encoder
+}
+
+func (b *ndjsonBundleWriter) writeManifest(m *Manifest) error {
+	if err := b.enc.Encode(ndjsonRecord{Type: "manifest", Manifest: m}); err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	return nil
+}
+
+func (b *ndjsonBundleWriter) writeFile(kind string, workflowID, runID int64, data []byte) error {
+	record := ndjsonRecord{
+		Type:       "jobs",
+		WorkflowID: workflowID,
+		RunID:      runID,
+		Data:       data,
+	}
+	if kind == "runs" {
+		record.Type = "run"
+	}
+	if err := b.enc.Encode(record); err != nil {
+		return fmt.Errorf("encoding %s of run #%d: %w", kind, runID, err)
+	}
+	return nil
+}
+
+func (b *ndjsonBundleWriter) Close() error {
+	return nil
+}
+
+// Import merges a bundle written by Export into the store. The bundle format
+// is detected from its content.
+func (s *Store) Import(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+
+	result := &ImportResult{}
+	var manifest *Manifest
+	save := func(kind string, workflowID, runID int64, data []byte) error {
+		if manifest == nil {
+			return errors.New("bundle does not start with a manifest")
+		}
+		switch kind {
+		case "runs":
+			if s.RunExists(workflowID, runID) && !opts.Overwrite {
+				result.SkippedRuns++
+				return nil
+			}
+			result.Runs++
+			return s.SaveRun(workflowID, runID, data)
+		case "jobs":
+			if s.JobExists(workflowID, runID) && !opts.Overwrite {
+				result.SkippedJobs++
+				return nil
+			}
+			result.Jobs++
+			return s.SaveJobs(workflowID, runID, data)
+		default:
+			return fmt.Errorf("unknown bundle entry kind %q", kind)
+		}
+	}
+	setManifest := func(m *Manifest) error {
+		if m.Version != bundleVersion {
+			return fmt.Errorf("unsupported bundle version %d", m.Version)
+		}
+		manifest = m
+		return nil
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		err = readTarBundle(br, setManifest, save)
+	} else {
+		err = readNDJSONBundle(br, setManifest, save)
+	}
+	if err != nil {
+		return result, err
+	}
+	if manifest == nil {
+		return result, errors.New("bundle has no manifest")
+	}
+	return result, nil
+}
+
+func readTarBundle(r io.Reader, setManifest func(*Manifest) error, save func(string, int64, int64, []byte) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("reading gzip: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("reading %q: %w", hdr.Name, err)
+		}
+
+		if hdr.Name == manifestName {
+			var m Manifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				return fmt.Errorf("decoding manifest: %w", err)
+			}
+			if err := setManifest(&m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		kind, workflowID, runID, ok := parseBundlePath(hdr.Name)
+		if !ok {
+			slog.Warn("skipping unknown bundle entry", "name", hdr.Name)
+			continue
+		}
+		if err := save(kind, workflowID, runID, data); err != nil {
+			return err
+		}
+	}
+}
+
+// parseBundlePath parses a tar entry name of the form workflows/<workflow ID>/<kind>/<run ID>.json.
+func parseBundlePath(name string) (kind string, workflowID, runID int64, ok bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != "workflows" || (parts[2] != "runs" && parts[2] != "jobs") || !strings.HasSuffix(parts[3], ".json") {
+		return "", 0, 0, false
+	}
+	workflowID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || workflowID <= 0 {
+		return "", 0, 0, false
+	}
+	runID, err = strconv.ParseInt(strings.TrimSuffix(parts[3], ".json"), 10, 64)
+	if err != nil || runID <= 0 {
+		return "", 0, 0, false
+	}
+	return parts[2], workflowID, runID, true
+}
+
+func readNDJSONBundle(r io.Reader, setManifest func(*Manifest) error, save func(string, int64, int64, []byte) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var record ndjsonRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("decoding bundle: %w", err)
+		}
+
+		switch record.Type {
+		case "manifest":
+			if record.Manifest == nil {
+				return errors.New("manifest record has no manifest")
+			}
+			if err := setManifest(record.Manifest); err != nil {
+				return err
+			}
+		case "run", "jobs":
+			if record.WorkflowID <= 0 || record.RunID <= 0 {
+				return fmt.Errorf("%s record is missing workflow_id or run_id", record.Type)
+			}
+			kind := "jobs"
+			if record.Type == "run" {
+				kind = "runs"
+			}
+			if err := save(kind, record.WorkflowID, record.RunID, record.Data); err != nil {
+				return err
+			}
+		default:
+			slog.Warn("skipping unknown bundle record", "type", record.Type)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []BundleFormat{BundleTarGz, BundleNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			src, err := NewStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustSave(t, src.SaveRun(10954, 1, []byte(`{"id":1,"created_at":"2021-10-01T10:00:00Z"}`)))
+			mustSave(t, src.SaveJobs(10954, 1, []byte(`{"total_count":0,"jobs":[]}`)))
+			mustSave(t, src.SaveRun(10954, 2, []byte(`{"id":2,"created_at":"2021-10-20T10:00:00Z"}`)))
+			mustSave(t, src.SaveRun(10954, 4, []byte(`{"id":4,"created_at":"2021-10-05T10:00:00Z"}`)))
+			mustSave(t, src.SaveJobs(10954, 4, []byte(`{"jobs":[`)))
+			mustSave(t, src.SaveRun(10954, 5, []byte(`{"id":5,"created_at":"2021-10-06T10:00:00Z"`)))
+			mustSave(t, src.SaveJobs(10954, 5, []byte(`{"jobs":[]}`)))
+			mustSave(t, src.SaveRun(42, 3, []byte(`{"id":3,"created_at":"2021-10-20T10:00:00Z"}`)))
+
+			var buf bytes.Buffer
+			manifest, err := src.Export(&buf, ExportOptions{
+				WorkflowIDs: []int64{10954},
+				From:        time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC),
+				To:          time.Date(2021, 10, 15, 0, 0, 0, 0, time.UTC),
+				Format:      format,
+			})
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			// runs and jobs with invalid JSON are neither written nor counted
+			want := ManifestWorkflow{WorkflowID: 10954, Runs: 2, Jobs: 1}
+			if len(manifest.Workflows) != 1 || manifest.Workflows[0] != want {
+				t.Errorf("Export() manifest workflows = %+v, want [%+v]", manifest.Workflows, want)
+			}
+
+			dst, err := NewStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustSave(t, dst.SaveRun(10954, 1, []byte(`{"id":1,"local":true}`)))
+
+			result, err := dst.Import(bytes.NewReader(buf.Bytes()), ImportOptions{})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if *result != (ImportResult{Runs: 1, Jobs: 1, SkippedRuns: 1}) {
+				t.Errorf("Import() = %+v", result)
+			}
+			if data, _ := dst.LoadRun(10954, 1); string(data) != `{"id":1,"local":true}` {
+				t.Errorf("Import() overwrote existing run: %s", data)
+			}
+			if dst.RunExists(10954, 2) || dst.RunExists(42, 3) {
+				t.Error("Import() imported runs that were not exported")
+			}
+			if dst.JobExists(10954, 4) {
+				t.Error("Import() imported jobs with invalid JSON")
+			}
+			if dst.RunExists(10954, 5) || dst.JobExists(10954, 5) {
+				t.Error("Import() imported run with invalid JSON")
+			}
+
+			result, err = dst.Import(bytes.NewReader(buf.Bytes()), ImportOptions{Overwrite: true})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if *result != (ImportResult{Runs: 2, Jobs: 1}) {
+				t.Errorf("Import() with overwrite = %+v", result)
+			}
+			if data, _ := dst.LoadRun(10954, 1); string(data) != `{"id":1,"created_at":"2021-10-01T10:00:00Z"}` {
+				t.Errorf("Import() with overwrite did not overwrite run: %s", data)
+			}
+		})
+	}
+}
+
+func mustSave(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IssueKind identifies the kind of inconsistency found in a store.
+type IssueKind string
+
+// Kinds of issues reported by Check.
+const (
+	IssueMissingJobs        IssueKind = "missing_jobs"
+	IssueOrphanJobs         IssueKind = "orphan_jobs"
+	IssueInvalidFileName    IssueKind = "invalid_file_name"
+	IssueInvalidRunJSON     IssueKind = "invalid_run_json"
+	IssueInvalidJobsJSON    IssueKind = "invalid_jobs_json"
+	IssueRunIDMismatch      IssueKind = "run_id_mismatch"
+	IssueWorkflowIDMismatch IssueKind = "workflow_id_mismatch"
+	IssueJobsRunIDMismatch  IssueKind = "jobs_run_id_mismatch"
+	IssueDuplicateRun       IssueKind = "duplicate_run"
+	IssueDuplicateJob       IssueKind = "duplicate_job"
+)
+
+// Issue describes a single inconsistency found in a store.
+type Issue struct {
+	Kind       IssueKind `json:"kind"`
+	WorkflowID int64     `json:"workflow_id"`
+	RunID      int64     `json:"run_id,omitempty"`
+	Path       string    `json:"path"`
+	Detail     string    `json:"detail"`
+}
+
+// storedRun holds the run fields needed to check consistency.
+type storedRun struct {
+	ID         *int64 `json:"id"`
+	WorkflowID *int64 `json:"workflow_id"`
+}
+
+// storedJobs holds the job fields needed to check consistency.
+type storedJobs struct {
+	Jobs []struct {
+		ID    int64 `json:"id"`
+		RunID int64 `json:"run_id"`
+	} `json:"jobs"`
+}
+
+// Check walks the store and reports inconsistencies between runs and jobs.
+// If no workflow IDs are given all workflows in the store are checked.
+func (s *Store) Check(workflowIDs ...int64) ([]Issue, error) {
+	if len(workflowIDs) == 0 {
+		ids, err := s.ListWorkflowIDs()
+		if err != nil {
+			return nil, err
+		}
+		workflowIDs = ids
+	}
+
+	c := &checker{
+		store:  s,
+		runIDs: make(map[int64]string),
+		jobIDs: make(map[int64]string),
+	}
+	for _, workflowID := range workflowIDs {
+		if err := c.checkRuns(workflowID); err != nil {
+			return c.results, err
+		}
+		if err := c.checkJobs(workflowID); err != nil {
+			return c.results, err
+		}
+	}
+	return c.results, nil
+}
+
+// checker accumulates issues and the IDs seen so far across workflows.
+type checker struct {
+	store   *Store
+	runIDs  map[int64]string
+	jobIDs  map[int64]string
+	results []Issue
+}
+
+func (c *checker) report(kind IssueKind, workflowID, runID int64, path, detail string) {
+	c.results = append(c.results, Issue{
+		Kind:       kind,
+		WorkflowID: workflowID,
+		RunID:      runID,
+		Path:       path,
+		Detail:     detail,
+	})
+}
+
+func (c *checker) checkRuns(workflowID int64) error {
+	dir := c.store.RunsDir(workflowID)
+	entries, err := readJSONEntries(dir)
+	if err != nil {
+		return fmt.Errorf("reading runs directory %q: %w", dir, err)
+	}
+
+	for _, name := range entries {
+		path := filepath.Join(dir, name)
+		runID, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			c.report(IssueInvalidFileName, workflowID, 0, path, "file name is not a run ID")
+			continue
+		}
+
+		if other, ok := c.runIDs[runID]; ok {
+			c.report(IssueDuplicateRun, workflowID, runID, path, "run is also stored in "+other)
+		} else {
+			c.runIDs[runID] = path
+		}
+
+		if !c.store.JobExists(workflowID, runID) {
+			c.report(IssueMissingJobs, workflowID, runID, path, "run has no jobs file")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading run file %q: %w", path, err)
+		}
+		var run storedRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			c.report(IssueInvalidRunJSON, workflowID, runID, path, err.Error())
+			continue
+		}
+		if run.ID == nil || *run.ID != runID {
+			c.report(IssueRunIDMismatch, workflowID, runID, path, fmt.Sprintf("run id is %s", formatOptionalID(run.ID)))
+		}
+		if run.WorkflowID == nil || *run.WorkflowID != workflowID {
+			c.report(IssueWorkflowIDMismatch, workflowID, runID, path, fmt.Sprintf("run workflow_id is %s", formatOptionalID(run.WorkflowID)))
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkJobs(workflowID int64) error {
+	dir := c.store.JobsDir(workflowID)
+	entries, err := readJSONEntries(dir)
+	if err != nil {
+		return fmt.Errorf("reading jobs directory %q: %w", dir, err)
+	}
+
+	for _, name := range entries {
+		path := filepath.Join(dir, name)
+		runID, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			c.report(IssueInvalidFileName, workflowID, 0, path, "file name is not a run ID")
+			continue
+		}
+
+		if !c.store.RunExists(workflowID, runID) {
+			c.report(IssueOrphanJobs, workflowID, runID, path, "jobs file has no run")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading jobs file %q: %w", path, err)
+		}
+		var jobs storedJobs
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			c.report(IssueInvalidJobsJSON, workflowID, runID, path, err.Error())
+			continue
+		}
+
+		mismatched := false
+		for _, job := range jobs.Jobs {
+			if job.RunID != runID && !mismatched {
+				mismatched = true
+				c.report(IssueJobsRunIDMismatch, workflowID, runID, path, fmt.Sprintf("job %d has run_id %d", job.ID, job.RunID))
+			}
+			if other, ok := c.jobIDs[job.ID]; ok {
+				c.report(IssueDuplicateJob, workflowID, runID, path, fmt.Sprintf("job %d is also stored in %s", job.ID, other))
+			} else {
+				c.jobIDs[job.ID] = path
+			}
+		}
+	}
+	return nil
+}
+
+// readJSONEntries returns the names of all JSON files in dir.
+// A missing directory yields no entries.
+func readJSONEntries(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+func formatOptionalID(id *int64) string {
+	if id == nil {
+		return "missing"
+	}
+	return strconv.FormatInt(*id, 10)
+}
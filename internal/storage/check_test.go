@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []IssueKind
+	}{
+		{
+			name: "consistent store has no issues",
+			files: map[string]string{
+				"workflows/10954/runs/1.json": `{"id": 1, "workflow_id": 10954}`,
+				"workflows/10954/jobs/1.json": `{"total_count": 1, "jobs": [{"id": 11, "run_id": 1}]}`,
+			},
+			want: nil,
+		},
+		{
+			name: "run without jobs",
+			files: map[string]string{
+				"workflows/10954/runs/1.json": `{"id": 1, "workflow_id": 10954}`,
+			},
+			want: []IssueKind{IssueMissingJobs},
+		},
+		{
+			name: "jobs without run",
+			files: map[string]string{
+				"workflows/10954/jobs/1.json": `{"total_count": 1, "jobs": [{"id": 11, "run_id": 1}]}`,
+			},
+			want: []IssueKind{IssueOrphanJobs},
+		},
+		{
+			name: "invalid JSON",
+			files: map[string]string{
+				"workflows/10954/runs/1.json": `{"id": 1,`,
+				"workflows/10954/jobs/1.json": `not json`,
+			},
+			want: []IssueKind{IssueInvalidRunJSON, IssueInvalidJobsJSON},
+		},
+		{
+			name: "mismatching IDs",
+			files: map[string]string{
+				"workflows/10954/runs/1.json": `{"id": 2, "workflow_id": 1}`,
+				"workflows/10954/jobs/1.json": `{"total_count": 1, "jobs": [{"id": 11, "run_id": 2}]}`,
+			},
+			want: []IssueKind{IssueRunIDMismatch, IssueWorkflowIDMismatch, IssueJobsRunIDMismatch},
+		},
+		{
+			name: "duplicated IDs",
+			files: map[string]string{
+				"workflows/1/runs/1.json":     `{"id": 1, "workflow_id": 1}`,
+				"workflows/1/jobs/1.json":     `{"total_count": 1, "jobs": [{"id": 11, "run_id": 1}]}`,
+				"workflows/2/runs/1.json":     `{"id": 1, "workflow_id": 2}`,
+				"workflows/2/jobs/1.json":     `{"total_count": 1, "jobs": [{"id": 11, "run_id": 1}]}`,
+				"workflows/2/runs/notes.json": `{}`,
+			},
+			want: []IssueKind{IssueDuplicateRun, IssueInvalidFileName, IssueDuplicateJob},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store, err := NewStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			issues, err := store.Check()
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			var got []IssueKind
+			for _, issue := range issues {
+				got = append(got, issue.Kind)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Check() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// IndexState records per kind of document the fingerprint of the stored
+// files each run was last indexed from. It allows indexing only runs whose
+// files are new or changed.
+type IndexState map[string]map[int64]string
+
+// IndexStatePath returns the file path of the index state of a workflow for
+// the given target. The target identifies where documents are indexed into.
+func (s *Store) IndexStatePath(target string, workflowID int64) string {
+	return filepath.Join(s.baseDir, "index-state", target, strconv.FormatInt(workflowID, 10)+".json")
+}
+
+// LoadIndexState loads the index state of a workflow for the given target.
+// A missing state is returned as an empty state.
+func (s *Store) LoadIndexState(target string, workflowID int64) (IndexState, error) {
+	path := s.IndexStatePath(target, workflowID)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return IndexState{}, nil
+		}
+		return nil, fmt.Errorf("reading index state %q: %w", path, err)
+	}
+
+	var state IndexState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding index state %q: %w", path, err)
+	}
+	if state == nil {
+		state = IndexState{}
+	}
+	return state, nil
+}
+
+// SaveIndexState saves the index state of a workflow for the given target.
+// The state is replaced atomically so concurrent readers never see a partial file.
+func (s *Store) SaveIndexState(target string, workflowID int64, state IndexState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding index state: %w", err)
+	}
+
+	path := s.IndexStatePath(target, workflowID)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".index-state-*")
+	if err != nil {
+		return fmt.Errorf("creating index state: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing index state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing index state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing index state %q: %w", path, err)
+	}
+	return nil
+}
+
+// Fingerprint returns a checksum over the contents of the given files.
+// Missing files contribute to the checksum so that a file appearing later
+// changes the fingerprint.
+func Fingerprint(paths ...string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("reading %q: %w", path, err)
+		}
+		if err != nil {
+			_, _ = hash.Write([]byte("missing"))
+		} else {
+			_, _ = hash.Write(data)
+		}
+		_, _ = hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ForgetIndexedRuns removes the given runs of a workflow from the index state
+// of all targets so that they are indexed again should they be stored again.
+func (s *Store) ForgetIndexedRuns(workflowID int64, runIDs []int64) error {
+	dir := filepath.Join(s.baseDir, "index-state")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading index state directory %q: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		target := entry.Name()
+		state, err := s.LoadIndexState(target, workflowID)
+		if err != nil {
+			return err
+		}
+		if len(state) == 0 {
+			continue
+		}
+		for _, fingerprints := range state {
+			for _, runID := range runIDs {
+				delete(fingerprints, runID)
+			}
+		}
+		if err := s.SaveIndexState(target, workflowID, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
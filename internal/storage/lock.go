@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	lockFileName     = ".gham.lock"
+	lockPollInterval = time.Second
+	// lockTakeoverSuffix is appended to the lock file name for the file
+	// guarding the removal of a stale lock.
+	lockTakeoverSuffix = ".takeover"
+	// lockTakeoverTimeout is the age after which a takeover file is
+	// considered abandoned.
+	lockTakeoverTimeout = 10 * time.Second
+)
+
+// LockError is returned by Lock if the store is locked by another process.
+type LockError struct {
+	Path  string
+	PID   int
+	Host  string
+	Since time.Time
+}
+
+func (e *LockError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("store lock file %s is empty or cannot be parsed, remove it if no gham process is running", e.Path)
+	}
+	return fmt.Sprintf("store is locked by process %d on %s since %s (lock file %s)", e.PID, e.Host, e.Since.Format(time.RFC3339), e.Path)
+}
+
+// stale reports whether the lock was left behind by a process on this host that no longer runs.
+func (e *LockError) stale() bool {
+	if e.PID == 0 {
+		return false
+	}
+	host, err := os.Hostname()
+	if err != nil || host != e.Host {
+		return false
+	}
+	return !processExists(e.PID)
+}
+
+// LockPath returns the path of the lock file guarding writes to the store.
+func (s *Store) LockPath() string {
+	return filepath.Join(s.baseDir, lockFileName)
+}
+
+// Lock takes an advisory lock on the store so that concurrent gham processes
+// do not write to the same files. If another process holds the lock, Lock
+// retries until wait has passed and then returns a *LockError. A lock left
+// behind by a process on this host that is no longer running is removed.
+// The returned function releases the lock.
+func (s *Store) Lock(ctx context.Context, wait time.Duration) (func() error, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		err := s.tryLock()
+		if err == nil {
+			return s.unlock, nil
+		}
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) || !time.Now().Before(deadline) {
+			return nil, err
+		}
+
+		slog.Debug("waiting for store lock", "pid", lockErr.PID, "host", lockErr.Host)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (s *Store) tryLock() error {
+	path := s.LockPath()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err == nil {
+		host, _ := os.Hostname()
+		_, werr := fmt.Fprintf(f, "%d\n%s\n%s\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+		cerr := f.Close()
+		if err := errors.Join(werr, cerr); err != nil {
+			_ = os.Remove(path)
+			return fmt.Errorf("writing lock file %q: %w", path, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("creating lock file %q: %w", path, err)
+	}
+
+	holder := readLock(path)
+	if !holder.stale() {
+		return holder
+	}
+	slog.Warn("removing stale store lock", "path", path, "pid", holder.PID)
+	if err := removeStaleLock(path, holder); err != nil {
+		return err
+	}
+	return s.tryLock()
+}
+
+// removeStaleLock removes the stale lock file at path held by holder.
+// Processes taking over a stale lock first create a takeover file
+// exclusively so that only one of them at a time checks and removes the lock
+// file. A lock file replaced by another process in the meantime is kept and
+// returned as a *LockError. While another process takes over the lock, holder
+// is returned so that the caller tries again.
+func removeStaleLock(path string, holder *LockError) error {
+	takeover := path + lockTakeoverSuffix
+	f, err := os.OpenFile(takeover, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("creating lock takeover file %q: %w", takeover, err)
+		}
+		// a process that died while taking over leaves the file behind
+		if info, err := os.Stat(takeover); err == nil && time.Since(info.ModTime()) > lockTakeoverTimeout {
+			slog.Warn("removing abandoned store lock takeover file", "path", takeover)
+			if err := os.Remove(takeover); err == nil {
+				return removeStaleLock(path, holder)
+			}
+		}
+		return holder
+	}
+	_ = f.Close()
+	defer func() { _ = os.Remove(takeover) }()
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	current := readLock(path)
+	if current.PID != holder.PID || current.Host != holder.Host || !current.Since.Equal(holder.Since) {
+		return current
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing stale lock file %q: %w", path, err)
+	}
+	return nil
+}
+
+// readLock reads the lock file at path. A lock file that cannot be read or
+// parsed, for example because it is just being written, yields a LockError
+// without a PID.
+func readLock(path string) *LockError {
+	lockErr := &LockError{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lockErr
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		return lockErr
+	}
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return lockErr
+	}
+	since, err := time.Parse(time.RFC3339, lines[2])
+	if err != nil {
+		return lockErr
+	}
+	lockErr.PID = pid
+	lockErr.Host = lines[1]
+	lockErr.Since = since
+	return lockErr
+}
+
+// unlock releases the lock if it is held by this process.
+func (s *Store) unlock() error {
+	path := s.LockPath()
+	holder := readLock(path)
+	if holder.PID != os.Getpid() {
+		return fmt.Errorf("lock file %q is not held by this process", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing lock file %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	t.Run("second lock fails while first is held", func(t *testing.T) {
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		unlock, err := store.Lock(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+
+		_, err = store.Lock(context.Background(), 0)
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) {
+			t.Fatalf("Lock() error = %v, want *LockError", err)
+		}
+		if lockErr.PID != os.Getpid() {
+			t.Errorf("Lock() error PID = %d, want %d", lockErr.PID, os.Getpid())
+		}
+
+		if err := unlock(); err != nil {
+			t.Fatalf("unlock() error = %v", err)
+		}
+		unlock, err = store.Lock(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("Lock() after unlock error = %v", err)
+		}
+		_ = unlock()
+	})
+
+	t.Run("stale lock is removed", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("stale locks are not detected on windows")
+		}
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, _ := os.Hostname()
+		stale := fmt.Sprintf("%d\n%s\n%s\n", 999999999, host, time.Now().UTC().Format(time.RFC3339))
+		if err := os.WriteFile(store.LockPath(), []byte(stale), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		unlock, err := store.Lock(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+		_ = unlock()
+	})
+
+	t.Run("stale lock taken over by another process is kept", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("stale locks are not detected on windows")
+		}
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, _ := os.Hostname()
+		since := time.Now().UTC().Truncate(time.Second)
+		stale := &LockError{Path: store.LockPath(), PID: 999999999, Host: host, Since: since}
+		// another process replaced the stale lock after it was read
+		taken := fmt.Sprintf("%d\n%s\n%s\n", os.Getppid(), host, since.Format(time.RFC3339))
+		if err := os.WriteFile(store.LockPath(), []byte(taken), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		err = removeStaleLock(store.LockPath(), stale)
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) || lockErr.PID != os.Getppid() {
+			t.Fatalf("removeStaleLock() error = %v, want *LockError of PID %d", err, os.Getppid())
+		}
+		data, err := os.ReadFile(store.LockPath())
+		if err != nil || string(data) != taken {
+			t.Errorf("removeStaleLock() left lock file %q, %v, want %q", data, err, taken)
+		}
+	})
+
+	t.Run("stale lock taken over by another process is left alone", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("stale locks are not detected on windows")
+		}
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, _ := os.Hostname()
+		stale := fmt.Sprintf("%d\n%s\n%s\n", 999999999, host, time.Now().UTC().Format(time.RFC3339))
+		if err := os.WriteFile(store.LockPath(), []byte(stale), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// another process is checking and removing the stale lock
+		takeover := store.LockPath() + lockTakeoverSuffix
+		if err := os.WriteFile(takeover, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = store.Lock(context.Background(), 0)
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) || lockErr.PID != 999999999 {
+			t.Fatalf("Lock() error = %v, want *LockError of PID 999999999", err)
+		}
+		data, err := os.ReadFile(store.LockPath())
+		if err != nil || string(data) != stale {
+			t.Errorf("Lock() left lock file %q, %v, want %q", data, err, stale)
+		}
+		if _, err := os.Stat(takeover); err != nil {
+			t.Errorf("Lock() removed the takeover file of another process: %v", err)
+		}
+
+		// the takeover file of a process that died is removed eventually
+		abandoned := time.Now().Add(-2 * lockTakeoverTimeout)
+		if err := os.Chtimes(takeover, abandoned, abandoned); err != nil {
+			t.Fatal(err)
+		}
+		unlock, err := store.Lock(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("Lock() with abandoned takeover file error = %v", err)
+		}
+		_ = unlock()
+		if _, err := os.Stat(takeover); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Lock() left takeover file behind: %v", err)
+		}
+	})
+
+	t.Run("unparseable lock is reported with its path", func(t *testing.T) {
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(store.LockPath(), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = store.Lock(context.Background(), 0)
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) {
+			t.Fatalf("Lock() error = %v, want *LockError", err)
+		}
+		if !strings.Contains(err.Error(), "cannot be parsed") || !strings.Contains(err.Error(), store.LockPath()) {
+			t.Errorf("Lock() error = %q, want unparseable lock file %s", err, store.LockPath())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// PrunePolicy selects stored runs for removal. A run is selected if any of
+// the configured rules matches it. Zero values disable a rule.
+type PrunePolicy struct {
+	// MaxAge selects runs created longer than MaxAge before Now.
+	MaxAge time.Duration
+	// KeepRuns selects all but the KeepRuns most recently created runs of a workflow.
+	KeepRuns int
+	// Branch selects runs whose head_branch matches the expression.
+	Branch *regexp.Regexp
+	// Now is the reference time for MaxAge. Defaults to the current time.
+	Now time.Time
+}
+
+// PruneCandidate is a stored run selected for removal.
+type PruneCandidate struct {
+	WorkflowID int64  `json:"workflow_id"`
+	RunID      int64  `json:"run_id"`
+	CreatedAt  string `json:"created_at"`
+	HeadBranch string `json:"head_branch"`
+	Reason     string `json:"reason"`
+}
+
+// runSummary holds the run fields needed to select runs.
+type runSummary struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	HeadBranch string    `json:"head_branch"`
+}
+
+// loadRunSummaries loads the summaries of all stored runs of a workflow.
+// Runs that cannot be parsed or lack a created_at time are skipped. The ID
+// is taken from the file name.
+func (s *Store) loadRunSummaries(workflowID int64) ([]runSummary, error) {
+	runIDs, err := s.ListStoredRunIDs(workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]runSummary, 0, len(runIDs))
+	for _, runID := range runIDs {
+		data, err := s.LoadRun(workflowID, runID)
+		if err != nil {
+			return nil, err
+		}
+		var run runSummary
+		if err := json.Unmarshal(data, &run); err != nil || run.CreatedAt.IsZero() {
+			continue
+		}
+		run.ID = runID
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// SelectPrunable returns the stored runs of a workflow that the policy selects for removal.
+// Runs that cannot be parsed, use Check to find them, or lack a created_at time
+// are never selected.
+func (s *Store) SelectPrunable(workflowID int64, policy PrunePolicy) ([]PruneCandidate, error) {
+	runs, err := s.loadRunSummaries(workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first so that the runs to keep come first
+	slices.SortStableFunc(runs, func(a, b runSummary) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	now := policy.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	cutoff := now.Add(-policy.MaxAge)
+
+	var candidates []PruneCandidate
+	for i, run := range runs {
+		var reason string
+		switch {
+		case policy.MaxAge > 0 && run.CreatedAt.Before(cutoff):
+			reason = "created before " + cutoff.Format(time.RFC3339)
+		case policy.KeepRuns > 0 && i >= policy.KeepRuns:
+			reason = fmt.Sprintf("not among the %d most recent runs", policy.KeepRuns)
+		case policy.Branch != nil && policy.Branch.MatchString(run.HeadBranch):
+			reason = fmt.Sprintf("branch matches %q", policy.Branch)
+		default:
+			continue
+		}
+		candidates = append(candidates, PruneCandidate{
+			WorkflowID: workflowID,
+			RunID:      run.ID,
+			CreatedAt:  run.CreatedAt.Format(time.RFC3339),
+			HeadBranch: run.HeadBranch,
+			Reason:     reason,
+		})
+	}
+	return candidates, nil
+}
+
+// RemoveRunAndJobs removes a stored run together with its jobs.
+func (s *Store) RemoveRunAndJobs(workflowID, runID int64) error {
+	if err := s.RemoveJobs(workflowID, runID); err != nil {
+		return err
+	}
+	return s.RemoveRun(workflowID, runID)
+}
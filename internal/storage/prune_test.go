@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSelectPrunable(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := map[int64]string{
+		1: `{"id": 1, "created_at": "2021-10-01T10:00:00Z", "head_branch": "master"}`,
+		2: `{"id": 2, "created_at": "2021-10-10T10:00:00Z", "head_branch": "dependabot/maven/junit"}`,
+		3: `{"id": 3, "created_at": "2021-10-20T10:00:00Z", "head_branch": "TECH-699"}`,
+		4: `{"id": 4, "created_at": "2021-10-21T10:00:00Z", "head_branch": "master"}`,
+		// runs without a created_at time are never selected
+		5: `{"id": 5, "head_branch": "master"}`,
+		6: `{"id": 6, "created_at": null, "head_branch": "dependabot/npm/lodash"}`,
+	}
+	for runID, data := range runs {
+		if err := store.SaveRun(10954, runID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2021, 10, 22, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		policy PrunePolicy
+		want   []int64
+	}{
+		{
+			name:   "max age",
+			policy: PrunePolicy{MaxAge: 7 * 24 * time.Hour, Now: now},
+			want:   []int64{2, 1},
+		},
+		{
+			name:   "keep runs",
+			policy: PrunePolicy{KeepRuns: 3, Now: now},
+			want:   []int64{1},
+		},
+		{
+			name:   "branch",
+			policy: PrunePolicy{Branch: regexp.MustCompile("^dependabot/"), Now: now},
+			want:   []int64{2},
+		},
+		{
+			name:   "any rule selects a run",
+			policy: PrunePolicy{KeepRuns: 3, Branch: regexp.MustCompile("^TECH-"), Now: now},
+			want:   []int64{3, 1},
+		},
+		{
+			name:   "no rules selects nothing",
+			policy: PrunePolicy{Now: now},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := store.SelectPrunable(10954, tt.policy)
+			if err != nil {
+				t.Fatalf("SelectPrunable() error = %v", err)
+			}
+
+			var got []int64
+			for _, candidate := range candidates {
+				got = append(got, candidate.RunID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SelectPrunable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
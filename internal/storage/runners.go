@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RunnersDir returns the directory path containing the snapshots of self-hosted runners.
+func (s *Store) RunnersDir() string {
+	return filepath.Join(s.baseDir, "runners")
+}
+
+// RunnersPath returns the file path of the snapshot of the self-hosted
+// runners of a repository, or of an organization if repo is empty.
+func (s *Store) RunnersPath(owner, repo string) string {
+	if repo == "" {
+		return filepath.Join(s.RunnersDir(), owner+".json")
+	}
+	return filepath.Join(s.RunnersDir(), owner, repo+".json")
+}
+
+// SaveRunners saves the snapshot of the self-hosted runners of a repository,
+// or of an organization if repo is empty, replacing the previous snapshot.
+func (s *Store) SaveRunners(owner, repo string, data json.RawMessage) error {
+	path := s.RunnersPath(owner, repo)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing runners file %q: %w", path, err)
+	}
+	return nil
+}
+
+// RunnersPaths returns the file paths of all stored snapshots of self-hosted
+// runners in lexical order.
+func (s *Store) RunnersPaths() ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(s.RunnersDir(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading runners directory %q: %w", s.RunnersDir(), err)
+	}
+	return paths, nil
+}
+
+// IterRunners iterates over all stored snapshots of self-hosted runners.
+func (s *Store) IterRunners() iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		paths, err := s.RunnersPaths()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if !yield(nil, fmt.Errorf("reading runners file %q: %w", path, err)) {
+					return
+				}
+				continue
+			}
+			if !yield(data, nil) {
+				return
+			}
+		}
+	}
+}
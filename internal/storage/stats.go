@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// WorkflowStats summarizes what is stored for a workflow.
+type WorkflowStats struct {
+	WorkflowID    int64          `json:"workflow_id"`
+	Runs          int            `json:"runs"`
+	Jobs          int            `json:"jobs"`
+	MissingJobs   int            `json:"missing_jobs"`
+	FirstRunAt    *time.Time     `json:"first_run_at,omitempty"`
+	LastRunAt     *time.Time     `json:"last_run_at,omitempty"`
+	Conclusions   map[string]int `json:"conclusions"`
+	Bytes         int64          `json:"bytes"`
+	LastFetchedAt *time.Time     `json:"last_fetched_at,omitempty"`
+}
+
+// Stats computes statistics about the runs and jobs stored for a workflow.
+// The last fetch time is the most recent modification time of a stored file.
+func (s *Store) Stats(workflowID int64) (*WorkflowStats, error) {
+	stats := &WorkflowStats{
+		WorkflowID:  workflowID,
+		Conclusions: make(map[string]int),
+	}
+
+	runIDs, err := s.ListStoredRunIDs(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Runs = len(runIDs)
+
+	missing, err := s.ListStoredRunIDsWithoutJobs(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	stats.MissingJobs = len(missing)
+
+	jobRunIDs, err := s.ListStoredJobRunIDs(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Jobs = len(jobRunIDs)
+
+	var first, last time.Time
+	for data, err := range s.IterRuns(workflowID) {
+		if err != nil {
+			slog.Warn("error reading run", "error", err)
+			continue
+		}
+		var run struct {
+			CreatedAt  time.Time `json:"created_at"`
+			Conclusion *string   `json:"conclusion"`
+		}
+		if err := json.Unmarshal(data, &run); err != nil {
+			stats.Conclusions["invalid"]++
+			continue
+		}
+
+		conclusion := "none"
+		if run.Conclusion != nil && *run.Conclusion != "" {
+			conclusion = *run.Conclusion
+		}
+		stats.Conclusions[conclusion]++
+
+		if run.CreatedAt.IsZero() {
+			continue
+		}
+		if first.IsZero() || run.CreatedAt.Before(first) {
+			first = run.CreatedAt
+		}
+		if last.IsZero() || run.CreatedAt.After(last) {
+			last = run.CreatedAt
+		}
+	}
+	if !first.IsZero() {
+		stats.FirstRunAt = &first
+		stats.LastRunAt = &last
+	}
+
+	var lastModified time.Time
+	for _, dir := range []string{s.RunsDir(workflowID), s.JobsDir(workflowID)} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("reading directory %q: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("reading file info of %q: %w", entry.Name(), err)
+			}
+			stats.Bytes += info.Size()
+			if info.ModTime().After(lastModified) {
+				lastModified = info.ModTime()
+			}
+		}
+	}
+	if !lastModified.IsZero() {
+		stats.LastFetchedAt = &lastModified
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	type file struct {
+		runID int64
+		data  string
+	}
+	tests := []struct {
+		name        string
+		runs        []file
+		jobs        []file
+		want        WorkflowStats
+		wantFirstAt time.Time
+		wantLastAt  time.Time
+	}{
+		{
+			name: "empty workflow",
+			want: WorkflowStats{WorkflowID: 10954, Conclusions: map[string]int{}},
+		},
+		{
+			name: "runs with and without jobs",
+			runs: []file{
+				{1, `{"id":1,"created_at":"2021-10-12T01:56:28Z","conclusion":"success"}`},
+				{2, `{"id":2,"created_at":"2021-10-01T10:00:00Z","conclusion":"failure"}`},
+				{3, `{"id":3,"created_at":"2021-10-20T10:00:00Z","conclusion":null}`},
+				{4, `{"id":4,"created_at":"2021-10-15T10:00:00Z","conclusion":"success"}`},
+			},
+			jobs: []file{
+				{1, `{"jobs":[]}`},
+				{2, `{"jobs":[]}`},
+			},
+			want: WorkflowStats{
+				WorkflowID:  10954,
+				Runs:        4,
+				Jobs:        2,
+				MissingJobs: 2,
+				Conclusions: map[string]int{"success": 2, "failure": 1, "none": 1},
+			},
+			wantFirstAt: time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC),
+			wantLastAt:  time.Date(2021, 10, 20, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid run and run without created_at",
+			runs: []file{
+				{1, `{"id":1,`},
+				{2, `{"id":2,"conclusion":"cancelled"}`},
+			},
+			jobs: []file{
+				{2, `{"jobs":[]}`},
+			},
+			want: WorkflowStats{
+				WorkflowID:  10954,
+				Runs:        2,
+				Jobs:        1,
+				MissingJobs: 1,
+				Conclusions: map[string]int{"invalid": 1, "cancelled": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bytes int64
+			for _, run := range tt.runs {
+				if err := store.SaveRun(10954, run.runID, []byte(run.data)); err != nil {
+					t.Fatal(err)
+				}
+				bytes += int64(len(run.data))
+			}
+			for _, jobs := range tt.jobs {
+				if err := store.SaveJobs(10954, jobs.runID, []byte(jobs.data)); err != nil {
+					t.Fatal(err)
+				}
+				bytes += int64(len(jobs.data))
+			}
+
+			got, err := store.Stats(10954)
+			if err != nil {
+				t.Fatalf("Stats() error = %v", err)
+			}
+			if got.WorkflowID != tt.want.WorkflowID || got.Runs != tt.want.Runs || got.Jobs != tt.want.Jobs || got.MissingJobs != tt.want.MissingJobs {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+			if !maps.Equal(got.Conclusions, tt.want.Conclusions) {
+				t.Errorf("Stats() conclusions = %v, want %v", got.Conclusions, tt.want.Conclusions)
+			}
+			if got.Bytes != bytes {
+				t.Errorf("Stats() bytes = %d, want %d", got.Bytes, bytes)
+			}
+			checkTime(t, "first_run_at", got.FirstRunAt, tt.wantFirstAt)
+			checkTime(t, "last_run_at", got.LastRunAt, tt.wantLastAt)
+			if (got.LastFetchedAt == nil) != (len(tt.runs) == 0 && len(tt.jobs) == 0) {
+				t.Errorf("Stats() last_fetched_at = %v", got.LastFetchedAt)
+			}
+		})
+	}
+}
+
+// checkTime checks that got is the time want or nil if want is zero.
+func checkTime(t *testing.T, field string, got *time.Time, want time.Time) {
+	t.Helper()
+	if want.IsZero() {
+		if got != nil {
+			t.Errorf("Stats() %s = %v, want none", field, got)
+		}
+		return
+	}
+	if got == nil || !got.Equal(want) {
+		t.Errorf("Stats() %s = %v, want %v", field, got, want)
+	}
+}
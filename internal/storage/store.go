@@ -97,6 +97,54 @@ func (s *Store) LoadJobs(workflowID, runID int64) (json.RawMessage, error) {
 	return data, nil
 }
 
+// RemoveRun removes a stored run. Removing a run that does not exist is not an error.
+func (s *Store) RemoveRun(workflowID, runID int64) error {
+	path := s.RunPath(workflowID, runID)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing run file %q: %w", path, err)
+	}
+	return nil
+}
+
+// RemoveJobs removes the stored jobs of a run. Removing jobs that do not exist is not an error.
+func (s *Store) RemoveJobs(workflowID, runID int64) error {
+	path := s.JobPath(workflowID, runID)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing jobs file %q: %w", path, err)
+	}
+	return nil
+}
+
+// WorkflowsDir returns the directory path containing all workflows.
+func (s *Store) WorkflowsDir() string {
+	return filepath.Join(s.baseDir, "workflows")
+}
+
+// ListWorkflowIDs returns the IDs of all workflows that have a directory in the store.
+func (s *Store) ListWorkflowIDs() ([]int64, error) {
+	dir := s.WorkflowsDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading workflows directory %q: %w", dir, err)
+	}
+
+	var workflowIDs []int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		workflowIDs = append(workflowIDs, id)
+	}
+	return workflowIDs, nil
+}
+
 // RunsDir returns the directory path for runs of a workflow.
 func (s *Store) RunsDir(workflowID int64) string {
 	return filepath.Join(s.baseDir, "workflows", strconv.FormatInt(workflowID, 10), "runs")
@@ -193,6 +241,32 @@ func (s *Store) ListStoredRunIDs(workflowID int64) ([]int64, error) {
 	return runIDs, nil
 }
 
+// ListStoredJobRunIDs returns the run IDs of all job files stored for a workflow.
+func (s *Store) ListStoredJobRunIDs(workflowID int64) ([]int64, error) {
+	dir := s.JobsDir(workflowID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading jobs directory %q: %w", dir, err)
+	}
+
+	var runIDs []int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		id, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		runIDs = append(runIDs, id)
+	}
+	return runIDs, nil
+}
+
 // ListStoredRunIDsWithoutJobs returns run IDs that don't have corresponding job files.
 func (s *Store) ListStoredRunIDsWithoutJobs(workflowID int64) ([]int64, error) {
 	runIDs, err := s.ListStoredRunIDs(workflowID)
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WorkflowFilePath returns the file path of the workflow file version with
+// the given content hash. Each distinct version is stored once.
+func (s *Store) WorkflowFilePath(hash string) string {
+	return filepath.Join(s.baseDir, "workflow-files", hash+".yml")
+}
+
+// WorkflowFileRefPath returns the file path recording the content hash of the
+// workflow file of a workflow at the given commit.
+func (s *Store) WorkflowFileRefPath(workflowID int64, headSHA string) string {
+	return filepath.Join(s.baseDir, "workflows", strconv.FormatInt(workflowID, 10), "workflow-files", headSHA)
+}
+
+// SaveWorkflowFile stores the workflow file of a workflow at the given commit
+// and returns the hash of its contents.
+func (s *Store) SaveWorkflowFile(workflowID int64, headSHA string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := s.WorkflowFilePath(hash)
+	stored, err := workflowFileStored(path, hash)
+	if err != nil {
+		return "", err
+	}
+	if !stored {
+		if err := writeWorkflowFile(path, data); err != nil {
+			return "", err
+		}
+	}
+
+	ref := s.WorkflowFileRefPath(workflowID, headSHA)
+	dir := filepath.Dir(ref)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+	if err := os.WriteFile(ref, []byte(hash), 0o600); err != nil {
+		return "", fmt.Errorf("writing workflow file ref %q: %w", ref, err)
+	}
+	return hash, nil
+}
+
+// workflowFileStored returns true if the workflow file at path exists and its
+// contents match the hash. A truncated or otherwise corrupt file is replaced.
+func workflowFileStored(path, hash string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading workflow file %q: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == hash, nil
+}
+
+// writeWorkflowFile writes the workflow file at path through a temporary file
+// so that it is never left partially written.
+func writeWorkflowFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".workflow-file-*")
+	if err != nil {
+		return fmt.Errorf("creating workflow file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing workflow file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing workflow file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing workflow file %q: %w", path, err)
+	}
+	return nil
+}
+
+// WorkflowFileHash returns the content hash of the workflow file of a
+// workflow at the given commit. It returns an empty hash if the workflow file
+// has not been stored.
+func (s *Store) WorkflowFileHash(workflowID int64, headSHA string) (string, error) {
+	path := s.WorkflowFileRefPath(workflowID, headSHA)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading workflow file ref %q: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// LoadWorkflowFile loads the workflow file version with the given content hash.
+func (s *Store) LoadWorkflowFile(hash string) ([]byte, error) {
+	data, err := os.ReadFile(s.WorkflowFilePath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("reading workflow file: %w", err)
+	}
+	return data, nil
+}
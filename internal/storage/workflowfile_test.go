@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveWorkflowFile(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		sha1 = "0d2e4c8b4f3a1e6d9c7b5a3f1e0d2c4b6a8f9e7d"
+		sha2 = "9f8e7d6c5b4a39281706f5e4d3c2b1a098765432"
+		sha3 = "1111111111111111111111111111111111111111"
+	)
+	content := []byte("name: CI\non: push\n")
+
+	hash1, err := store.SaveWorkflowFile(10954, sha1, content)
+	if err != nil {
+		t.Fatalf("SaveWorkflowFile() error = %v", err)
+	}
+	hash2, err := store.SaveWorkflowFile(10954, sha2, content)
+	if err != nil {
+		t.Fatalf("SaveWorkflowFile() error = %v", err)
+	}
+	if hash1 != hash2 {
+		t.Errorf("SaveWorkflowFile() = %q and %q for the same content, want the same hash", hash1, hash2)
+	}
+
+	blobs, err := os.ReadDir(filepath.Dir(store.WorkflowFilePath(hash1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 1 {
+		t.Errorf("SaveWorkflowFile() stored %d workflow files, want 1", len(blobs))
+	}
+	for _, sha := range []string{sha1, sha2} {
+		got, err := store.WorkflowFileHash(10954, sha)
+		if err != nil {
+			t.Fatalf("WorkflowFileHash(%q) error = %v", sha, err)
+		}
+		if got != hash1 {
+			t.Errorf("WorkflowFileHash(%q) = %q, want %q", sha, got, hash1)
+		}
+	}
+
+	data, err := store.LoadWorkflowFile(hash1)
+	if err != nil {
+		t.Fatalf("LoadWorkflowFile() error = %v", err)
+	}
+	if string(data) != string(content) {
+		t.Errorf("LoadWorkflowFile() = %q, want %q", data, content)
+	}
+
+	got, err := store.WorkflowFileHash(10954, sha3)
+	if err != nil {
+		t.Fatalf("WorkflowFileHash() of missing ref error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("WorkflowFileHash() of missing ref = %q, want empty", got)
+	}
+
+	// a workflow file truncated by a crash is repaired by the next save
+	if err := os.WriteFile(store.WorkflowFilePath(hash1), content[:5], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveWorkflowFile(10954, sha1, content); err != nil {
+		t.Fatalf("SaveWorkflowFile() error = %v", err)
+	}
+	data, err = store.LoadWorkflowFile(hash1)
+	if err != nil {
+		t.Fatalf("LoadWorkflowFile() error = %v", err)
+	}
+	if string(data) != string(content) {
+		t.Errorf("LoadWorkflowFile() after repair = %q, want %q", data, content)
+	}
+}
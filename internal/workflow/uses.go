@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RefType is the kind of ref an action or reusable workflow is referenced at.
+type RefType string
+
+// Kinds of refs. Refs are not resolved against the referenced repository so
+// tags and branches are told apart by whether the ref looks like a version.
+const (
+	RefSHA    RefType = "sha"
+	RefTag    RefType = "tag"
+	RefBranch RefType = "branch"
+	// RefLocal references an action or workflow in the same repository which
+	// is versioned together with the workflow.
+	RefLocal RefType = "local"
+	// RefDocker references a Docker image by tag or digest.
+	RefDocker RefType = "docker"
+)
+
+var (
+	shaPattern     = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	versionPattern = regexp.MustCompile(`^v?\d+(\.\d+)*([-+][0-9A-Za-z.-]+)?$`)
+)
+
+// Reference is a parsed uses value of a step or job.
+type Reference struct {
+	Uses string
+	// Action is the referenced action or workflow without the ref like
+	// actions/checkout, ./.github/actions/build or alpine for docker images.
+	Action string
+	Ref    string
+	Type   RefType
+}
+
+// ParseReference parses the uses value of a step or job.
+func ParseReference(uses string) Reference {
+	ref := Reference{Uses: uses}
+	switch {
+	case strings.HasPrefix(uses, "./"):
+		ref.Action = uses
+		ref.Type = RefLocal
+	case strings.HasPrefix(uses, "docker://"):
+		image := strings.TrimPrefix(uses, "docker://")
+		ref.Type = RefDocker
+		if name, digest, ok := strings.Cut(image, "@"); ok {
+			ref.Action, ref.Ref = name, digest
+		} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			ref.Action, ref.Ref = image[:i], image[i+1:]
+		} else {
+			ref.Action = image
+		}
+	default:
+		ref.Action, ref.Ref, _ = strings.Cut(uses, "@")
+		switch {
+		case shaPattern.MatchString(ref.Ref):
+			ref.Type = RefSHA
+		case versionPattern.MatchString(ref.Ref):
+			ref.Type = RefTag
+		default:
+			ref.Type = RefBranch
+		}
+	}
+	return ref
+}
+
+// Pinned reports whether the reference always resolves to the same code.
+// Only commit SHAs, image digests and references within the same repository
+// are pinned. Tags and branches can be moved.
+func (r Reference) Pinned() bool {
+	switch r.Type {
+	case RefSHA, RefLocal:
+		return true
+	case RefDocker:
+		return strings.HasPrefix(r.Ref, "sha256:")
+	}
+	return false
+}
+
+// Use is a reference to an action or reusable workflow in a workflow.
+type Use struct {
+	// Job is the ID of the job.
+	Job string
+	// Step is the number of the step starting at 1, or 0 if the job calls a
+	// reusable workflow.
+	Step int
+	Reference
+}
+
+// Uses returns the references to actions and reusable workflows of all jobs
+// ordered by job ID and step.
+func (w *Workflow) Uses() []Use {
+	var uses []Use
+	for id, job := range w.Jobs {
+		if job.Uses != "" {
+			uses = append(uses, Use{Job: id, Reference: ParseReference(job.Uses)})
+		}
+		for i, step := range job.Steps {
+			if step.Uses != "" {
+				uses = append(uses, Use{Job: id, Step: i + 1, Reference: ParseReference(step.Uses)})
+			}
+		}
+	}
+	slices.SortFunc(uses, func(a, b Use) int {
+		return cmp.Or(strings.Compare(a.Job, b.Job), cmp.Compare(a.Step, b.Step))
+	})
+	return uses
+}
@@ -0,0 +1,37 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		uses       string
+		wantAction string
+		wantRef    string
+		wantType   RefType
+		wantPinned bool
+	}{
+		{"actions/checkout@v4", "actions/checkout", "v4", RefTag, false},
+		{"actions/setup-java@v4.2.1", "actions/setup-java", "v4.2.1", RefTag, false},
+		{"actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11", "actions/checkout", "b4ffde65f46336ab88eb53be808477a3936bae11", RefSHA, true},
+		{"dhis2/action-tools/setup@main", "dhis2/action-tools/setup", "main", RefBranch, false},
+		{"octo-org/workflows/.github/workflows/build.yml@release/1.x", "octo-org/workflows/.github/workflows/build.yml", "release/1.x", RefBranch, false},
+		{"./.github/actions/build", "./.github/actions/build", "", RefLocal, true},
+		{"docker://alpine:3.8", "alpine", "3.8", RefDocker, false},
+		{"docker://localhost:5000/tools", "localhost:5000/tools", "", RefDocker, false},
+		{"docker://alpine@sha256:1e014f84205d569a5cc3be4e108ca614055f7e21d11928946113ab3f36054801", "alpine", "sha256:1e014f84205d569a5cc3be4e108ca614055f7e21d11928946113ab3f36054801", RefDocker, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			got := ParseReference(tt.uses)
+			if got.Action != tt.wantAction || got.Ref != tt.wantRef || got.Type != tt.wantType {
+				t.Errorf("ParseReference() = %+v, want action %q ref %q type %q", got, tt.wantAction, tt.wantRef, tt.wantType)
+			}
+			if got.Pinned() != tt.wantPinned {
+				t.Errorf("Pinned() = %t, want %t", got.Pinned(), tt.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package workflow parses GitHub Actions workflow files.
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a parsed GitHub Actions workflow file. Only the parts used for
+// analysis are parsed.
+type Workflow struct {
+	Name string          `yaml:"name"`
+	Jobs map[string]*Job `yaml:"jobs"`
+}
+
+// Job is a job of a workflow.
+type Job struct {
+	// ID is the key of the job in the jobs map.
+	ID   string `yaml:"-"`
+	Name string `yaml:"name"`
+	// Needs are the IDs of the jobs that must complete before this job runs.
+	Needs StringList `yaml:"needs"`
+	// Uses references the reusable workflow the job calls.
+	Uses     string   `yaml:"uses"`
+	Steps    []Step   `yaml:"steps"`
+	Strategy Strategy `yaml:"strategy"`
+}
+
+// Strategy is the strategy of a job.
+type Strategy struct {
+	Matrix Matrix `yaml:"matrix"`
+}
+
+// Matrix is the matrix of a job. Only the keys are parsed.
+type Matrix struct {
+	// Defined is true if the job has a matrix.
+	Defined bool
+	// Keys are the matrix keys in the order GitHub lists their values in the
+	// names of matrix jobs. Keys only added by include entries come last.
+	// Keys are nil if the matrix is given as an expression.
+	Keys []string
+}
+
+// UnmarshalYAML decodes the keys of a matrix mapping in order.
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	m.Defined = value.Tag != "!!null"
+	if value.Kind != yaml.MappingNode {
+		return nil
+	}
+	var include *yaml.Node
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		switch key := value.Content[i].Value; key {
+		case "include":
+			include = value.Content[i+1]
+		case "exclude":
+		default:
+			m.Keys = append(m.Keys, key)
+		}
+	}
+	if include == nil || include.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range include.Content {
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i < len(entry.Content); i += 2 {
+			if key := entry.Content[i].Value; !slices.Contains(m.Keys, key) {
+				m.Keys = append(m.Keys, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Step is a step of a job.
+type Step struct {
+	Name string `yaml:"name"`
+	// Uses references the action the step runs.
+	Uses string `yaml:"uses"`
+}
+
+// StringList is a YAML value given either as a single string or a list of strings.
+type StringList []string
+
+// UnmarshalYAML decodes a single string or a sequence of strings.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = StringList{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*l = list
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a string or a list of strings", value.Line)
+}
+
+// Parse parses a workflow file.
+func Parse(data []byte) (*Workflow, error) {
+	var workflow Workflow
+	if err := yaml.Unmarshal(data, &workflow); err != nil {
+		return nil, fmt.Errorf("parsing workflow: %w", err)
+	}
+	for id, job := range workflow.Jobs {
+		if job == nil {
+			job = &Job{}
+			workflow.Jobs[id] = job
+		}
+		job.ID = id
+		for _, need := range job.Needs {
+			if _, ok := workflow.Jobs[need]; !ok {
+				return nil, fmt.Errorf("job %q needs unknown job %q", id, need)
+			}
+		}
+	}
+	if err := workflow.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// checkCycles returns an error if jobs need each other in a cycle.
+func (w *Workflow) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(w.Jobs))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("job %q needs itself through a cycle", id)
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, need := range w.Jobs[id].Needs {
+			if err := visit(need); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+	for id := range w.Jobs {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JobFor returns the workflow job a job of a run with the given name was
+// created from, or nil if it cannot be determined. GitHub names jobs of a run
+// by the name of the workflow job, or its ID if it has none, followed by the
+// matrix values in parentheses for matrix jobs. Jobs of called reusable
+// workflows are prefixed by the name of the calling job and a slash.
+func (w *Workflow) JobFor(name string) *Job {
+	name, _, _ = strings.Cut(name, " / ")
+	base := BaseName(name)
+	for _, candidate := range []string{name, base} {
+		for _, job := range w.Jobs {
+			if job.Name == candidate || (job.Name == "" && job.ID == candidate) {
+				return job
+			}
+		}
+	}
+	return nil
+}
+
+// SplitName returns the base name and matrix values of a job of a run like
+// BaseName and MatrixValues. Names of workflow jobs that end in parentheses
+// themselves or of jobs without a matrix are not split. Names of jobs that
+// cannot be found, like those of called reusable workflows, are split.
+func (w *Workflow) SplitName(name string) (string, []string) {
+	base, values := BaseName(name), MatrixValues(name)
+	if w == nil || values == nil || strings.Contains(name, " / ") {
+		return base, values
+	}
+	job := w.JobFor(name)
+	if job == nil {
+		return base, values
+	}
+	if job.Name == name || (job.Name == "" && job.ID == name) || !job.Strategy.Matrix.Defined {
+		return name, nil
+	}
+	return base, values
+}
+
+// BaseName returns the name of a job of a run without the matrix values in
+// parentheses GitHub appends to names of matrix jobs.
+func BaseName(name string) string {
+	if i := matrixStart(name); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// MatrixValues returns the matrix values in parentheses GitHub appends to
+// names of matrix jobs like test (ubuntu-latest, 17). It returns nil for jobs
+// without matrix values.
+func MatrixValues(name string) []string {
+	i := matrixStart(name)
+	if i <= 0 {
+		return nil
+	}
+	return strings.Split(name[i+len(" ("):len(name)-1], ", ")
+}
+
+// matrixStart returns the index of the matrix values in the name of a job of
+// a run or -1 if it has none.
+func matrixStart(name string) int {
+	if !strings.HasSuffix(name, ")") {
+		return -1
+	}
+	return strings.LastIndex(name, " (")
+}
+
+// Matrix returns the matrix values of a job of a run by matrix key. It returns
+// nil if the workflow job is unknown or the number of values in the name does
+// not match the number of matrix keys. Matrix values of jobs of called
+// reusable workflows are not mapped as their matrix is defined elsewhere.
+func (w *Workflow) Matrix(name string) map[string]string {
+	values := MatrixValues(name)
+	if values == nil || strings.Contains(name, " / ") {
+		return nil
+	}
+	job := w.JobFor(name)
+	if job == nil || len(job.Strategy.Matrix.Keys) != len(values) {
+		return nil
+	}
+	matrix := make(map[string]string, len(values))
+	for i, key := range job.Strategy.Matrix.Keys {
+		matrix[key] = values[i]
+	}
+	return matrix
+}
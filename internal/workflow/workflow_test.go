@@ -0,0 +1,141 @@
+package workflow
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+  test:
+    name: Test
+    needs: build
+  deploy:
+    needs: [build, test]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if wf.Name != "CI" || len(wf.Jobs) != 3 {
+		t.Fatalf("Parse() = %+v", wf)
+	}
+	if needs := wf.Jobs["deploy"].Needs; len(needs) != 2 || needs[0] != "build" || needs[1] != "test" {
+		t.Errorf("Parse() deploy needs = %v", needs)
+	}
+
+	tests := map[string]string{
+		"build":                    "build",
+		"Test (ubuntu-latest, 17)": "test",
+		"deploy / publish":         "deploy",
+		"unknown":                  "",
+	}
+	for name, want := range tests {
+		var got string
+		if job := wf.JobFor(name); job != nil {
+			got = job.ID
+		}
+		if got != want {
+			t.Errorf("JobFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown need": "jobs:\n  a:\n    needs: b\n",
+		"cycle":        "jobs:\n  a:\n    needs: b\n  b:\n    needs: a\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Error("Parse() expected error")
+			}
+		})
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	wf, err := Parse([]byte(`
+jobs:
+  test:
+    strategy:
+      matrix:
+        os: [ubuntu-latest, windows-latest]
+        java: [17, 21]
+        exclude:
+          - os: windows-latest
+            java: 17
+        include:
+          - os: ubuntu-latest
+            java: 21
+            experimental: true
+  dynamic:
+    strategy:
+      matrix: ${{ fromJson(needs.setup.outputs.matrix) }}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := map[string]map[string]string{
+		"test (ubuntu-latest, 21, true)": {"os": "ubuntu-latest", "java": "21", "experimental": "true"},
+		"test (ubuntu-latest, 17)":       nil,
+		"dynamic (a, b)":                 nil,
+		"test":                           nil,
+	}
+	for name, want := range tests {
+		if got := wf.Matrix(name); !maps.Equal(got, want) {
+			t.Errorf("Matrix(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if got, want := MatrixValues("test (ubuntu-latest, 17)"), []string{"ubuntu-latest", "17"}; !slices.Equal(got, want) {
+		t.Errorf("MatrixValues() = %v, want %v", got, want)
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	wf, err := Parse([]byte(`
+jobs:
+  test:
+    strategy:
+      matrix:
+        java: [17, 21]
+  dynamic:
+    strategy:
+      matrix: ${{ fromJson(needs.setup.outputs.matrix) }}
+  deploy:
+    name: deploy (production)
+  lint: {}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		wf         *Workflow
+		wantBase   string
+		wantValues []string
+	}{
+		{name: "test (17)", wf: wf, wantBase: "test", wantValues: []string{"17"}},
+		{name: "dynamic (a, b)", wf: wf, wantBase: "dynamic", wantValues: []string{"a", "b"}},
+		{name: "deploy (production)", wf: wf, wantBase: "deploy (production)"},
+		{name: "lint (fast)", wf: wf, wantBase: "lint (fast)"},
+		{name: "call / test (17)", wf: wf, wantBase: "call / test", wantValues: []string{"17"}},
+		{name: "unknown (17)", wf: wf, wantBase: "unknown", wantValues: []string{"17"}},
+		{name: "deploy (production)", wantBase: "deploy", wantValues: []string{"production"}},
+	}
+	for _, tt := range tests {
+		base, values := tt.wf.SplitName(tt.name)
+		if base != tt.wantBase || !slices.Equal(values, tt.wantValues) {
+			t.Errorf("SplitName(%q) = %q, %v, want %q, %v", tt.name, base, values, tt.wantBase, tt.wantValues)
+		}
+	}
+}